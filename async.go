@@ -0,0 +1,303 @@
+package kubernetes
+
+import (
+	"errors"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/v2/js/modules"
+
+	"github.com/grafana/xk6-kubernetes/pkg/api"
+	"github.com/grafana/xk6-kubernetes/pkg/helpers"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+// async executes the operation in a new goroutine and returns a promise that is resolved with the
+// result of the operation or rejected with its error
+func async(vu modules.VU, operation func() (interface{}, error)) *sobek.Promise {
+	rt := vu.Runtime()
+	promise, resolve, reject := rt.NewPromise()
+	callback := vu.RegisterCallback()
+	go func() {
+		result, err := operation()
+		callback(func() error {
+			if err != nil {
+				// the error is created in the event loop, as the runtime is not thread-safe
+				return reject(newError(rt, err))
+			}
+			return resolve(result)
+		})
+	}()
+	return promise
+}
+
+// apiResourcesAsync is the asynchronous version of APIResources. It is exposed with the APIResourcesAsync field
+func (k *Kubernetes) apiResourcesAsync(options ...api.APIResourcesOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.APIResources(options...)
+	})
+}
+
+// ApplyAsync is the asynchronous version of Apply
+func (k *Kubernetes) ApplyAsync(manifest string, options ...resources.ApplyOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.Apply(manifest, options...)
+	})
+}
+
+// ApplyObjectAsync is the asynchronous version of ApplyObject
+func (k *Kubernetes) ApplyObjectAsync(obj map[string]interface{}, options ...resources.ApplyOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.ApplyObject(obj, options...)
+	})
+}
+
+// CanIAsync is the asynchronous version of CanI
+func (k *Kubernetes) CanIAsync(verb string, kind string, options ...api.AccessReviewOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.CanI(verb, kind, options...)
+	})
+}
+
+// CreateAsync is the asynchronous version of Create
+func (k *Kubernetes) CreateAsync(obj map[string]interface{}) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.Create(obj)
+	})
+}
+
+// DeleteAsync is the asynchronous version of Delete
+func (k *Kubernetes) DeleteAsync(
+	kind string,
+	name string,
+	namespace string,
+	options ...resources.DeleteOptions,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return nil, k.Delete(kind, name, namespace, options...)
+	})
+}
+
+// DeleteCollectionAsync is the asynchronous version of DeleteCollection
+func (k *Kubernetes) DeleteCollectionAsync(
+	kind string,
+	namespace string,
+	options ...resources.DeleteCollectionOptions,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.DeleteCollection(kind, namespace, options...)
+	})
+}
+
+// GetAsync is the asynchronous version of Get
+func (k *Kubernetes) GetAsync(kind string, name string, namespace string) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.Get(kind, name, namespace)
+	})
+}
+
+// GetSubresourceAsync is the asynchronous version of GetSubresource
+func (k *Kubernetes) GetSubresourceAsync(
+	kind string,
+	name string,
+	namespace string,
+	subresource string,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.GetSubresource(kind, name, namespace, subresource)
+	})
+}
+
+// HasKindAsync is the asynchronous version of HasKind
+func (k *Kubernetes) HasKindAsync(kind string) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.HasKind(kind)
+	})
+}
+
+// ListAsync is the asynchronous version of List
+func (k *Kubernetes) ListAsync(kind string, namespace string, options ...resources.ListOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.List(kind, namespace, options...)
+	})
+}
+
+// ListPageAsync is the asynchronous version of ListPage
+func (k *Kubernetes) ListPageAsync(kind string, namespace string, options ...resources.ListOptions) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.ListPage(kind, namespace, options...)
+	})
+}
+
+// PatchAsync is the asynchronous version of Patch
+func (k *Kubernetes) PatchAsync(
+	kind string,
+	name string,
+	namespace string,
+	patch interface{},
+	options ...resources.PatchOptions,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.Patch(kind, name, namespace, patch, options...)
+	})
+}
+
+// PatchSubresourceAsync is the asynchronous version of PatchSubresource
+func (k *Kubernetes) PatchSubresourceAsync(
+	kind string,
+	name string,
+	namespace string,
+	subresource string,
+	patch interface{},
+	options ...resources.PatchOptions,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.PatchSubresource(kind, name, namespace, subresource, patch, options...)
+	})
+}
+
+// RefreshDiscoveryAsync is the asynchronous version of RefreshDiscovery
+func (k *Kubernetes) RefreshDiscoveryAsync() *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.RefreshDiscovery(), nil
+	})
+}
+
+// RulesForAsync is the asynchronous version of RulesFor
+func (k *Kubernetes) RulesForAsync(namespace string) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.RulesFor(namespace)
+	})
+}
+
+// ServerVersionAsync is the asynchronous version of ServerVersion
+func (k *Kubernetes) ServerVersionAsync() *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.ServerVersion()
+	})
+}
+
+// SubjectAccessReviewAsync is the asynchronous version of SubjectAccessReview
+func (k *Kubernetes) SubjectAccessReviewAsync(
+	user string,
+	groups []string,
+	verb string,
+	kind string,
+	options ...api.AccessReviewOptions,
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.SubjectAccessReview(user, groups, verb, kind, options...)
+	})
+}
+
+// UpdateAsync is the asynchronous version of Update
+func (k *Kubernetes) UpdateAsync(obj map[string]interface{}) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.Update(obj)
+	})
+}
+
+// UpdateSubresourceAsync is the asynchronous version of UpdateSubresource
+func (k *Kubernetes) UpdateSubresourceAsync(
+	kind string,
+	subresource string,
+	obj map[string]interface{},
+) *sobek.Promise {
+	return async(k.vu, func() (interface{}, error) {
+		return k.UpdateSubresource(kind, subresource, obj)
+	})
+}
+
+// ExecuteInPodAsync is the asynchronous version of ExecuteInPod
+func (h *Helpers) ExecuteInPodAsync(options helpers.PodExecOptions) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.ExecuteInPod(options)
+	})
+}
+
+// GetExternalIPAsync is the asynchronous version of GetExternalIP
+func (h *Helpers) GetExternalIPAsync(service string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.GetExternalIP(service, timeout)
+	})
+}
+
+// RolloutHistoryAsync is the asynchronous version of RolloutHistory
+func (h *Helpers) RolloutHistoryAsync(kind string, name string) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.RolloutHistory(kind, name)
+	})
+}
+
+// RolloutPauseAsync is the asynchronous version of RolloutPause
+func (h *Helpers) RolloutPauseAsync(kind string, name string) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.RolloutPause(kind, name)
+	})
+}
+
+// RolloutRestartAsync is the asynchronous version of RolloutRestart
+func (h *Helpers) RolloutRestartAsync(kind string, name string) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.RolloutRestart(kind, name)
+	})
+}
+
+// RolloutResumeAsync is the asynchronous version of RolloutResume
+func (h *Helpers) RolloutResumeAsync(kind string, name string) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.RolloutResume(kind, name)
+	})
+}
+
+// RolloutUndoAsync is the asynchronous version of RolloutUndo
+func (h *Helpers) RolloutUndoAsync(kind string, name string, revision int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.RolloutUndo(kind, name, revision)
+	})
+}
+
+// WaitCRDEstablishedAsync is the asynchronous version of WaitCRDEstablished
+func (h *Helpers) WaitCRDEstablishedAsync(name string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.WaitCRDEstablished(name, timeout)
+	})
+}
+
+// WaitForAsync is the asynchronous version of WaitFor. Predicates are not supported, as JavaScript functions
+// cannot be called outside of the event loop
+func (h *Helpers) WaitForAsync(kind string, name string, options ...helpers.WaitForOptions) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		if len(options) > 0 && options[0].Predicate != nil {
+			return nil, errors.New("predicates are not supported by waitForAsync, use waitFor instead")
+		}
+		return h.WaitFor(kind, name, options...)
+	})
+}
+
+// WaitJobCompletedAsync is the asynchronous version of WaitJobCompleted
+func (h *Helpers) WaitJobCompletedAsync(name string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.WaitJobCompleted(name, timeout)
+	})
+}
+
+// WaitPodRunningAsync is the asynchronous version of WaitPodRunning
+func (h *Helpers) WaitPodRunningAsync(name string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.WaitPodRunning(name, timeout)
+	})
+}
+
+// WaitRolloutCompleteAsync is the asynchronous version of WaitRolloutComplete
+func (h *Helpers) WaitRolloutCompleteAsync(kind string, name string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.WaitRolloutComplete(kind, name, timeout)
+	})
+}
+
+// WaitServiceReadyAsync is the asynchronous version of WaitServiceReady
+func (h *Helpers) WaitServiceReadyAsync(service string, timeout int64) *sobek.Promise {
+	return async(h.vu, func() (interface{}, error) {
+		return h.WaitServiceReady(service, timeout)
+	})
+}
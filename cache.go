@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+// sharedClients holds the clients for a cluster, which are safe for concurrent use and can be shared
+// by the VUs. The clients share the transport and the discovery cache. Unless the rate limiter is shared,
+// each VU uses clients with its own rate limiter, returned by forVU
+type sharedClients struct {
+	config *clientConfig
+	// httpClient sends the requests of the clients. It does not impersonate any identity, so it can be shared
+	// by clients impersonating different identities
+	httpClient *http.Client
+	clientset  kubernetes.Interface
+	dynamic    dynamic.Interface
+	mapper     meta.RESTMapper
+	discovery  discovery.CachedDiscoveryInterface
+	// sharedRateLimiter indicates the clientset and dynamic client are used by all the VUs
+	sharedRateLimiter bool
+	metrics           *kubernetesMetrics
+}
+
+// newSharedClients creates the clients for the cluster given in the options
+func newSharedClients(options KubeConfig, m *kubernetesMetrics) (*sharedClients, error) {
+	config, err := getClientConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = configureRateLimiter(config.rest, options, m)
+	if err != nil {
+		return nil, err
+	}
+	kinds := &resourceKinds{}
+	config.rest.Wrap(newMetricsTransport(m, kinds))
+
+	base := rest.CopyConfig(config.rest)
+	base.Impersonate = rest.ImpersonationConfig{}
+	httpClient, err := rest.HTTPClientFor(base)
+	if err != nil {
+		return nil, err
+	}
+	impersonating := withImpersonation(httpClient, config.rest.Impersonate)
+
+	clientset, err := kubernetes.NewForConfigAndClient(config.rest, impersonating)
+	if err != nil {
+		return nil, err
+	}
+	dynamic, err := dynamic.NewForConfigAndClient(config.rest, impersonating)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfigAndClient(config.rest, impersonating)
+	if err != nil {
+		return nil, err
+	}
+
+	// the RESTMapper and the discovery operations share the cache, so refreshing the discovery invalidates
+	// both. The RESTMapper also expands the short names of the resources (e.g. "deploy"). As the cache is
+	// shared by the VUs, the concurrent refreshes triggered by unknown kinds are deduplicated
+	cachedDiscovery := memory.NewMemCacheClient(discoveryClient)
+	mapper := resources.NewSharedRESTMapper(
+		restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery), cachedDiscovery, nil),
+	)
+	kinds.setMapper(mapper)
+
+	return &sharedClients{
+		config:            config,
+		httpClient:        httpClient,
+		clientset:         clientset,
+		dynamic:           dynamic,
+		mapper:            mapper,
+		discovery:         cachedDiscovery,
+		sharedRateLimiter: options.SharedRateLimiter,
+		metrics:           m,
+	}, nil
+}
+
+// forVU returns the clients used by a VU. They share the transport, the RESTMapper and the discovery cache
+// with these clients but, unless the rate limiter is shared, have their own rate limiter, so the QPS and
+// burst limits apply to each VU
+func (s *sharedClients) forVU() (*sharedClients, error) {
+	// injected clients for unit testing have no transport to create clients with
+	if s.sharedRateLimiter || s.httpClient == nil || s.config.rest.RateLimiter == nil {
+		return s, nil
+	}
+
+	config := *s.config
+	config.rest = rest.CopyConfig(s.config.rest)
+	config.rest.RateLimiter = newRateLimiter(config.rest.QPS, config.rest.Burst, s.metrics)
+	httpClient := withImpersonation(s.httpClient, config.rest.Impersonate)
+
+	derived := *s
+	derived.config = &config
+
+	var err error
+	derived.clientset, err = kubernetes.NewForConfigAndClient(config.rest, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	derived.dynamic, err = dynamic.NewForConfigAndClient(config.rest, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &derived, nil
+}
+
+// clientCache caches the clients by their configuration
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]*sharedClients
+}
+
+// get returns the cached clients for the options, creating them if they are not cached. Returns true if
+// the clients were cached
+func (c *clientCache) get(options KubeConfig, m *kubernetesMetrics) (*sharedClients, bool, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, false, err
+	}
+	key := string(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if clients, found := c.clients[key]; found {
+		return clients, true, nil
+	}
+
+	clients, err := newSharedClients(options, m)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.clients == nil {
+		c.clients = map[string]*sharedClients{}
+	}
+	c.clients[key] = clients
+
+	return clients, false, nil
+}
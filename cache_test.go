@@ -0,0 +1,77 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestClientCache(t *testing.T) {
+	t.Parallel()
+
+	content, err := clientcmd.Write(*buildKubeconfig())
+	require.NoError(t, err)
+
+	cache := &clientCache{}
+
+	primary, hit, err := cache.get(KubeConfig{Config: string(content)}, nil)
+	require.NoError(t, err)
+	require.False(t, hit)
+
+	cached, hit, err := cache.get(KubeConfig{Config: string(content)}, nil)
+	require.NoError(t, err)
+	require.True(t, hit)
+	require.Same(t, primary, cached)
+
+	secondary, hit, err := cache.get(KubeConfig{Config: string(content), Context: "secondary"}, nil)
+	require.NoError(t, err)
+	require.False(t, hit)
+	require.NotSame(t, primary, secondary)
+	require.Equal(t, "https://secondary:6443", secondary.config.cluster.Server)
+
+	// clients that fail to be created are not cached
+	_, _, err = cache.get(KubeConfig{Config: string(content), Context: "unknown"}, nil)
+	require.Error(t, err)
+	require.Len(t, cache.clients, 2)
+}
+
+func TestClientsForVURateLimiter(t *testing.T) {
+	t.Parallel()
+
+	content, err := clientcmd.Write(*buildKubeconfig())
+	require.NoError(t, err)
+
+	cache := &clientCache{}
+
+	shared, _, err := cache.get(KubeConfig{Config: string(content)}, nil)
+	require.NoError(t, err)
+
+	first, err := shared.forVU()
+	require.NoError(t, err)
+	second, err := shared.forVU()
+	require.NoError(t, err)
+
+	// each VU has its own rate limiter, but shares the transport and the discovery
+	require.NotSame(t, first.config.rest.RateLimiter, second.config.rest.RateLimiter)
+	require.NotSame(t, shared.config.rest.RateLimiter, first.config.rest.RateLimiter)
+	require.Equal(t, shared.config.rest.QPS, first.config.rest.QPS)
+	require.NotSame(t, shared.clientset, first.clientset)
+	require.Same(t, shared.httpClient, first.httpClient)
+	require.Equal(t, shared.mapper, first.mapper)
+	require.Same(t, shared.discovery, first.discovery)
+
+	// the rate limiter is shared if requested
+	shared, _, err = cache.get(KubeConfig{Config: string(content), SharedRateLimiter: true}, nil)
+	require.NoError(t, err)
+	vu, err := shared.forVU()
+	require.NoError(t, err)
+	require.Same(t, shared, vu)
+
+	// the rate limiter is disabled in all the clients
+	shared, _, err = cache.get(KubeConfig{Config: string(content), RateLimiter: "none"}, nil)
+	require.NoError(t, err)
+	vu, err = shared.forVU()
+	require.NoError(t, err)
+	require.Nil(t, vu.config.rest.RateLimiter)
+}
@@ -0,0 +1,392 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+// KubeConfig represents the initialization settings for the kubernetes api client.
+type KubeConfig struct {
+	// ConfigPath is the location of the kubeconfig. It can be a list of files separated by the OS path list
+	// separator, as in the KUBECONFIG environment variable, which are merged
+	ConfigPath string
+	// Config is the content of the kubeconfig
+	Config string `js:"config"`
+	Server string
+	Token  string
+	// Context is the name of the kubeconfig context to use. Defaults to the current context
+	Context string `js:"context"`
+	// Cluster is the name of the kubeconfig cluster to use instead of the one in the context
+	Cluster string `js:"cluster"`
+	// User is the name of the kubeconfig user to use instead of the one in the context
+	User string `js:"user"`
+	// Namespace is the default namespace. Defaults to the namespace of the kubeconfig context or, when running
+	// in-cluster, to the namespace of the service account
+	Namespace string `js:"namespace"`
+	// TokenFile is the path of a file with the bearer token. The file is read again periodically, so the
+	// token can be rotated
+	TokenFile string `js:"tokenFile"`
+	// CAData is the PEM-encoded certificate authority used for verifying the server certificate
+	CAData string `js:"caData"`
+	// CAFile is the path of a PEM-encoded certificate authority file
+	CAFile string `js:"caFile"`
+	// CertData is the PEM-encoded client certificate for authenticating to the server
+	CertData string `js:"certData"`
+	// KeyData is the PEM-encoded key of the client certificate
+	KeyData string `js:"keyData"`
+	// Insecure disables the verification of the server certificate
+	Insecure bool `js:"insecure"`
+	// TLSServerName is the server name used for verifying the server certificate. Defaults to the server host
+	TLSServerName string `js:"tlsServerName"`
+	// ProxyURL is the URL of the proxy used for connecting to the server
+	ProxyURL string `js:"proxyURL"`
+	// Timeout is the maximum time in seconds for a request to the server, except for watches and other
+	// requests that stream their responses. 0 means no timeout
+	Timeout int64 `js:"timeout"`
+	// QPS is the maximum number of queries per second sent by the client. Defaults to 5
+	QPS float32 `js:"qps"`
+	// Burst is the maximum number of queries sent at once by the client. Defaults to 10
+	Burst int `js:"burst"`
+	// RateLimiter can be set to "none" for disabling the client-side rate limiter
+	RateLimiter string `js:"rateLimiter"`
+	// SharedRateLimiter makes the clients shared by the VUs also share the rate limiter, so the QPS and
+	// burst limits apply to all of them instead of to each VU
+	SharedRateLimiter bool `js:"sharedRateLimiter"`
+	// DisableCache disables sharing the clients with other clients created with the same options
+	DisableCache bool `js:"disableCache"`
+	// Impersonate is the identity impersonated in the requests to the server
+	Impersonate ImpersonateConfig `js:"impersonate"`
+}
+
+// ImpersonateConfig describes the identity impersonated in the requests to the server
+type ImpersonateConfig struct {
+	// User is the name of the impersonated user. Required for impersonating groups or extra fields
+	User   string   `js:"user"`
+	Groups []string `js:"groups"`
+	UID    string   `js:"uid"`
+	// Extra contains additional information of the impersonated user (e.g. scopes)
+	Extra map[string][]string `js:"extra"`
+}
+
+// ClusterInfo describes the cluster a client is connected to
+type ClusterInfo struct {
+	// Context is the name of the kubeconfig context used. Empty if not connected using a kubeconfig
+	Context string `js:"context"`
+	// Cluster is the name of the kubeconfig cluster used. Empty if not connected using a kubeconfig
+	Cluster string `js:"cluster"`
+	// Server is the URL of the Kubernetes API server
+	Server string `js:"server"`
+}
+
+// ContextInfo describes a context of a kubeconfig
+type ContextInfo struct {
+	Name      string `js:"name"`
+	Cluster   string `js:"cluster"`
+	User      string `js:"user"`
+	Namespace string `js:"namespace"`
+	// Current is true if the context is the current context of the kubeconfig
+	Current bool `js:"current"`
+}
+
+// hasServerOptions returns true if any of the options that only apply when the server is given is set
+func (options KubeConfig) hasServerOptions() bool {
+	return options.Token != "" || options.TokenFile != "" || options.CAData != "" || options.CAFile != "" ||
+		options.CertData != "" || options.KeyData != "" || options.Insecure || options.TLSServerName != ""
+}
+
+// hasKubeconfigOptions returns true if any of the options for selecting from the kubeconfig is set
+func (options KubeConfig) hasKubeconfigOptions() bool {
+	return options.Context != "" || options.Cluster != "" || options.User != ""
+}
+
+// clientConfig holds the settings for creating the clients for a cluster
+type clientConfig struct {
+	rest *rest.Config
+	// cluster describes the cluster the clients are connected to
+	cluster ClusterInfo
+	// namespace is the default namespace for the clients
+	namespace string
+}
+
+// getClientConfig returns the settings for connecting to the cluster given in the options
+func getClientConfig(options KubeConfig) (*clientConfig, error) {
+	if options.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must be a positive number of seconds: %d", options.Timeout)
+	}
+
+	config, err := buildConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	config.cluster.Server = config.rest.Host
+	if options.Namespace != "" {
+		config.namespace = options.Namespace
+	}
+
+	// the timeout is applied by the transport instead of the http.Client, so it does not end watches
+	if options.Timeout > 0 {
+		config.rest.Wrap(newTimeoutTransport(time.Duration(options.Timeout) * time.Second))
+	}
+	if options.Impersonate.isSet() {
+		config.rest.Impersonate, err = options.Impersonate.toImpersonationConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.ProxyURL != "" {
+		proxy, err := parseProxyURL(options.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		config.rest.Proxy = http.ProxyURL(proxy)
+	}
+
+	return config, nil
+}
+
+func buildConfig(options KubeConfig) (*clientConfig, error) {
+	// If server is provided, use it with the given credentials
+	if options.Server != "" {
+		if options.hasKubeconfigOptions() {
+			return nil, errors.New("context, cluster and user options cannot be used with the server option")
+		}
+		config, err := getServerConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		return &clientConfig{rest: config, namespace: resources.DefaultNamespace}, nil
+	}
+	if options.hasServerOptions() {
+		return nil, errors.New("token, tokenFile, TLS and insecure options require the server option")
+	}
+
+	if options.Config != "" && options.ConfigPath != "" {
+		return nil, errors.New("config and config_path options cannot be used together")
+	}
+
+	// If server is not provided, use kubeconfig
+	if options.Config == "" && options.ConfigPath == "" && !options.hasKubeconfigOptions() {
+		// are we in-cluster?
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return &clientConfig{rest: config, namespace: inClusterNamespace()}, nil
+		}
+	}
+
+	// we aren't in-cluster
+	var (
+		raw *clientcmdapi.Config
+		err error
+	)
+	if options.Config != "" {
+		raw, err = clientcmd.Load([]byte(options.Config))
+	} else {
+		raw, err = loadKubeconfig(options.ConfigPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return selectFromKubeconfig(raw, options)
+}
+
+// inClusterNamespace returns the namespace of the pod's service account when running in-cluster
+func inClusterNamespace() string {
+	// with an empty kubeconfig, the namespace is taken from the in-cluster configuration
+	namespace, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{},
+		&clientcmd.ConfigOverrides{},
+	).Namespace()
+	if err != nil || namespace == "" {
+		return resources.DefaultNamespace
+	}
+	return namespace
+}
+
+// loadKubeconfig loads the kubeconfig from the given path. The path can be a list of files, which are merged
+// following the same rules as kubectl. If no path is given, the KUBECONFIG environment variable is used and,
+// if it is not set, the kubeconfig in the home directory
+func loadKubeconfig(path string) (*clientcmdapi.Config, error) {
+	return kubeconfigLoadingRules(path).Load()
+}
+
+// kubeconfigLoadingRules returns the rules for loading the kubeconfig from the given path. Unlike kubectl,
+// the kubeconfig in legacy locations is not migrated, as loading the kubeconfig must not write any file
+func kubeconfigLoadingRules(path string) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.MigrationRules = nil
+
+	paths := filepath.SplitList(path)
+	switch len(paths) {
+	case 0:
+	case 1:
+		// a single file must exist
+		rules.ExplicitPath = paths[0]
+	default:
+		rules.Precedence = paths
+	}
+
+	return rules
+}
+
+// selectFromKubeconfig returns the settings for the context, cluster and user selected from a loaded kubeconfig
+func selectFromKubeconfig(raw *clientcmdapi.Config, options KubeConfig) (*clientConfig, error) {
+	contextName := raw.CurrentContext
+	if options.Context != "" {
+		contextName = options.Context
+	}
+	context, found := raw.Contexts[contextName]
+	if !found && options.Context != "" {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", options.Context)
+	}
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: options.Context,
+		Context: clientcmdapi.Context{
+			Cluster:  options.Cluster,
+			AuthInfo: options.User,
+		},
+	}
+	selected := clientcmd.NewNonInteractiveClientConfig(*raw, contextName, overrides, nil)
+	config, err := selected.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := selected.Namespace()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := ClusterInfo{Context: contextName, Cluster: options.Cluster}
+	if found && cluster.Cluster == "" {
+		cluster.Cluster = context.Cluster
+	}
+
+	return &clientConfig{rest: config, cluster: cluster, namespace: namespace}, nil
+}
+
+// getContexts returns the contexts of the kubeconfig, which is loaded as in loadKubeconfig
+func getContexts(path string) ([]ContextInfo, error) {
+	raw, err := loadKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return listContexts(raw), nil
+}
+
+// listContexts returns the contexts of a loaded kubeconfig sorted by name
+func listContexts(raw *clientcmdapi.Config) []ContextInfo {
+	contexts := make([]ContextInfo, 0, len(raw.Contexts))
+	for name, context := range raw.Contexts {
+		contexts = append(contexts, ContextInfo{
+			Name:      name,
+			Cluster:   context.Cluster,
+			User:      context.AuthInfo,
+			Namespace: context.Namespace,
+			Current:   name == raw.CurrentContext,
+		})
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+
+	return contexts
+}
+
+// getServerConfig returns the config for connecting to the server with the credentials and TLS settings
+// given in the options
+func getServerConfig(options KubeConfig) (*rest.Config, error) {
+	if options.Token != "" && options.TokenFile != "" {
+		return nil, errors.New("token and tokenFile options cannot be used together")
+	}
+	if options.Token == "" && options.TokenFile == "" && options.CertData == "" {
+		return nil, errors.New("server option requires token, tokenFile or certData and keyData options")
+	}
+	if options.TokenFile != "" {
+		if _, err := transport.NewCachedFileTokenSource(options.TokenFile).Token(); err != nil {
+			return nil, fmt.Errorf("invalid tokenFile option: %w", err)
+		}
+	}
+
+	tlsConfig, err := getTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rest.Config{
+		Host:            options.Server,
+		BearerToken:     options.Token,
+		BearerTokenFile: options.TokenFile,
+		TLSClientConfig: tlsConfig,
+	}, nil
+}
+
+// getTLSConfig validates the TLS options and returns them as a TLS client config
+func getTLSConfig(options KubeConfig) (rest.TLSClientConfig, error) {
+	if options.CAData != "" && options.CAFile != "" {
+		return rest.TLSClientConfig{}, errors.New("caData and caFile options cannot be used together")
+	}
+	if options.Insecure && (options.CAData != "" || options.CAFile != "") {
+		return rest.TLSClientConfig{}, errors.New("insecure option cannot be used with caData or caFile options")
+	}
+	if options.CAData != "" {
+		if _, err := certutil.NewPoolFromBytes([]byte(options.CAData)); err != nil {
+			return rest.TLSClientConfig{}, fmt.Errorf("invalid caData option: %w", err)
+		}
+	}
+	if options.CAFile != "" {
+		if _, err := certutil.NewPool(options.CAFile); err != nil {
+			return rest.TLSClientConfig{}, fmt.Errorf("invalid caFile option: %w", err)
+		}
+	}
+	if (options.CertData == "") != (options.KeyData == "") {
+		return rest.TLSClientConfig{}, errors.New("certData and keyData options must be used together")
+	}
+	if options.CertData != "" {
+		if _, err := tls.X509KeyPair([]byte(options.CertData), []byte(options.KeyData)); err != nil {
+			return rest.TLSClientConfig{}, fmt.Errorf("invalid certData or keyData option: %w", err)
+		}
+	}
+
+	return rest.TLSClientConfig{
+		Insecure:   options.Insecure,
+		ServerName: options.TLSServerName,
+		CAData:     []byte(options.CAData),
+		CAFile:     options.CAFile,
+		CertData:   []byte(options.CertData),
+		KeyData:    []byte(options.KeyData),
+	}, nil
+}
+
+// parseProxyURL parses and validates the URL of the proxy
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxyURL option: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxyURL option %q: scheme must be http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxyURL option %q: missing host", proxyURL)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,285 @@
+package kubernetes
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestGetClientConfig(t *testing.T) {
+	t.Parallel()
+
+	cert, key, err := certutil.GenerateSelfSignedCertKey("localhost", nil, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		test        string
+		options     KubeConfig
+		expectError bool
+	}{
+		{
+			test:    "token",
+			options: KubeConfig{Server: "https://localhost:6443", Token: "token"},
+		},
+		{
+			test: "token with certificate authority",
+			options: KubeConfig{
+				Server:        "https://localhost:6443",
+				Token:         "token",
+				CAData:        string(cert),
+				TLSServerName: "localhost",
+			},
+		},
+		{
+			test: "client certificate",
+			options: KubeConfig{
+				Server:   "https://localhost:6443",
+				CertData: string(cert),
+				KeyData:  string(key),
+			},
+		},
+		{
+			test:        "no credentials",
+			options:     KubeConfig{Server: "https://localhost:6443"},
+			expectError: true,
+		},
+		{
+			test:        "token without server",
+			options:     KubeConfig{Token: "token"},
+			expectError: true,
+		},
+		{
+			test:        "token and token file",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", TokenFile: "/token"},
+			expectError: true,
+		},
+		{
+			test:        "missing token file",
+			options:     KubeConfig{Server: "https://localhost:6443", TokenFile: "/nonexistent/token"},
+			expectError: true,
+		},
+		{
+			test:        "insecure with certificate authority",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", Insecure: true, CAData: string(cert)},
+			expectError: true,
+		},
+		{
+			test:        "invalid certificate authority",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", CAData: "invalid"},
+			expectError: true,
+		},
+		{
+			test:        "missing certificate authority file",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", CAFile: "/nonexistent/ca.crt"},
+			expectError: true,
+		},
+		{
+			test:        "certificate without key",
+			options:     KubeConfig{Server: "https://localhost:6443", CertData: string(cert)},
+			expectError: true,
+		},
+		{
+			test:        "invalid proxy",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", ProxyURL: "ftp://proxy"},
+			expectError: true,
+		},
+		{
+			test:        "negative timeout",
+			options:     KubeConfig{Server: "https://localhost:6443", Token: "token", Timeout: -1},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := getClientConfig(tc.options)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.options.Server, config.rest.Host)
+			require.False(t, config.rest.Insecure)
+			require.Equal(t, "default", config.namespace)
+		})
+	}
+}
+
+func TestGetClientConfigWithProxyAndTimeout(t *testing.T) {
+	t.Parallel()
+
+	config, err := getClientConfig(KubeConfig{
+		Server:   "https://localhost:6443",
+		Token:    "token",
+		ProxyURL: "http://proxy:3128",
+		Timeout:  30,
+	})
+	require.NoError(t, err)
+	// the timeout is applied by the transport, so it does not end watches
+	require.Zero(t, config.rest.Timeout)
+	transport, isTimeout := config.rest.WrapTransport(http.DefaultTransport).(*timeoutTransport)
+	require.True(t, isTimeout)
+	require.Equal(t, 30*time.Second, transport.timeout)
+
+	req, err := http.NewRequest(http.MethodGet, "https://localhost:6443/api", nil)
+	require.NoError(t, err)
+	proxy, err := config.rest.Proxy(req)
+	require.NoError(t, err)
+	require.Equal(t, "proxy:3128", proxy.Host)
+}
+
+func buildKubeconfig() *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["primary"] = &clientcmdapi.Cluster{Server: "https://primary:6443"}
+	config.Clusters["secondary"] = &clientcmdapi.Cluster{Server: "https://secondary:6443"}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "admin-token"}
+	config.AuthInfos["viewer"] = &clientcmdapi.AuthInfo{Token: "viewer-token"}
+	config.Contexts["primary"] = &clientcmdapi.Context{Cluster: "primary", AuthInfo: "admin", Namespace: "testns"}
+	config.Contexts["secondary"] = &clientcmdapi.Context{Cluster: "secondary", AuthInfo: "admin"}
+	config.CurrentContext = "primary"
+	return config
+}
+
+func TestSelectFromKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test              string
+		options           KubeConfig
+		expectError       bool
+		expectedCluster   ClusterInfo
+		expectedToken     string
+		expectedNamespace string
+	}{
+		{
+			test:              "current context",
+			options:           KubeConfig{},
+			expectedCluster:   ClusterInfo{Context: "primary", Cluster: "primary", Server: "https://primary:6443"},
+			expectedToken:     "admin-token",
+			expectedNamespace: "testns",
+		},
+		{
+			test:              "select context",
+			options:           KubeConfig{Context: "secondary"},
+			expectedCluster:   ClusterInfo{Context: "secondary", Cluster: "secondary", Server: "https://secondary:6443"},
+			expectedToken:     "admin-token",
+			expectedNamespace: "default",
+		},
+		{
+			test:              "override cluster and user",
+			options:           KubeConfig{Cluster: "secondary", User: "viewer"},
+			expectedCluster:   ClusterInfo{Context: "primary", Cluster: "secondary", Server: "https://secondary:6443"},
+			expectedToken:     "viewer-token",
+			expectedNamespace: "testns",
+		},
+		{
+			test:        "unknown context",
+			options:     KubeConfig{Context: "unknown"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := selectFromKubeconfig(buildKubeconfig(), tc.options)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			config.cluster.Server = config.rest.Host
+			require.Equal(t, tc.expectedCluster, config.cluster)
+			require.Equal(t, tc.expectedToken, config.rest.BearerToken)
+			require.Equal(t, tc.expectedNamespace, config.namespace)
+		})
+	}
+}
+
+func TestListContexts(t *testing.T) {
+	t.Parallel()
+
+	contexts := listContexts(buildKubeconfig())
+	require.Equal(t, []ContextInfo{
+		{Name: "primary", Cluster: "primary", User: "admin", Namespace: "testns", Current: true},
+		{Name: "secondary", Cluster: "secondary", User: "admin"},
+	}, contexts)
+}
+
+func TestInlineKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	content, err := clientcmd.Write(*buildKubeconfig())
+	require.NoError(t, err)
+
+	config, err := getClientConfig(KubeConfig{Config: string(content), Context: "secondary"})
+	require.NoError(t, err)
+	require.Equal(t, ClusterInfo{Context: "secondary", Cluster: "secondary", Server: "https://secondary:6443"}, config.cluster)
+	require.Equal(t, "admin-token", config.rest.BearerToken)
+
+	config, err = getClientConfig(KubeConfig{Config: string(content), Namespace: "override"})
+	require.NoError(t, err)
+	require.Equal(t, "override", config.namespace)
+
+	_, err = getClientConfig(KubeConfig{Config: string(content), ConfigPath: "/path/to/kubeconfig"})
+	require.Error(t, err)
+}
+
+func TestKubeconfigPathList(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// the contexts are split in two files, each with its cluster and user
+	primary := buildKubeconfig()
+	delete(primary.Contexts, "secondary")
+	delete(primary.Clusters, "secondary")
+	secondary := buildKubeconfig()
+	delete(secondary.Contexts, "primary")
+	delete(secondary.Clusters, "primary")
+	secondary.CurrentContext = "secondary"
+
+	primaryPath := filepath.Join(dir, "primary")
+	secondaryPath := filepath.Join(dir, "secondary")
+	require.NoError(t, clientcmd.WriteToFile(*primary, primaryPath))
+	require.NoError(t, clientcmd.WriteToFile(*secondary, secondaryPath))
+
+	path := primaryPath + string(filepath.ListSeparator) + secondaryPath
+	contexts, err := getContexts(path)
+	require.NoError(t, err)
+	require.Len(t, contexts, 2)
+
+	// the current context is taken from the first file
+	config, err := getClientConfig(KubeConfig{ConfigPath: path})
+	require.NoError(t, err)
+	require.Equal(t, "primary", config.cluster.Context)
+
+	config, err = getClientConfig(KubeConfig{ConfigPath: path, Context: "secondary"})
+	require.NoError(t, err)
+	require.Equal(t, "https://secondary:6443", config.cluster.Server)
+
+	_, err = getClientConfig(KubeConfig{ConfigPath: filepath.Join(dir, "missing")})
+	require.Error(t, err)
+}
+
+func TestKubeconfigLoadingRulesDoNotMigrate(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{
+		"",
+		"/path/to/kubeconfig",
+		"/path/to/a" + string(filepath.ListSeparator) + "/path/to/b",
+	}
+	for _, path := range paths {
+		require.Empty(t, kubeconfigLoadingRules(path).MigrationRules, path)
+	}
+}
@@ -0,0 +1,181 @@
+package kubernetes
+
+import (
+	"errors"
+
+	"github.com/grafana/sobek"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newError returns a JavaScript error for the given error. If the error was returned by the Kubernetes
+// API server, the error has the reason, code and details of the status, and the suggested number of
+// seconds to wait before retrying. Errors applying manifests also have the applied objects and the
+// errors of the documents that failed
+func newError(rt *sobek.Runtime, err error) *sobek.Object {
+	jsErr := rt.NewGoError(err)
+
+	var (
+		details           interface{}
+		retryAfterSeconds interface{}
+	)
+
+	var applyErr *resources.ApplyError
+	if errors.As(err, &applyErr) {
+		_ = jsErr.Set("applied", applyErr.Applied)
+		_ = jsErr.Set("errors", documentErrors(applyErr))
+		// the status is only described if a single document failed
+		if len(applyErr.Errors) == 1 {
+			err = applyErr.Errors[0]
+		}
+	}
+
+	reason, code := errorStatus(err)
+	if applyErr != nil && len(applyErr.Errors) > 1 {
+		reason, code = applyErrorStatus(applyErr)
+	} else {
+		var apiStatus apierrors.APIStatus
+		if errors.As(err, &apiStatus) && apiStatus.Status().Details != nil {
+			details = statusDetails(apiStatus.Status().Details)
+		}
+		if delay, found := apierrors.SuggestsClientDelay(err); found {
+			retryAfterSeconds = delay
+		}
+	}
+
+	_ = jsErr.Set("reason", string(reason))
+	_ = jsErr.Set("code", code)
+	_ = jsErr.Set("details", details)
+	_ = jsErr.Set("retryAfterSeconds", retryAfterSeconds)
+
+	return jsErr
+}
+
+// errorStatus returns the reason and code of the status returned by the Kubernetes API server, if any
+func errorStatus(err error) (metav1.StatusReason, int32) {
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return metav1.StatusReasonUnknown, 0
+	}
+	status := apiStatus.Status()
+	return status.Reason, status.Code
+}
+
+// applyErrorStatus returns the reason and code shared by all the documents that failed to be applied. If the
+// documents failed for different reasons, the reason is unknown and the reason of each document is given in
+// the errors
+func applyErrorStatus(err *resources.ApplyError) (metav1.StatusReason, int32) {
+	if len(err.Errors) == 0 {
+		return metav1.StatusReasonUnknown, 0
+	}
+
+	reason, code := errorStatus(err.Errors[0])
+	for _, docErr := range err.Errors[1:] {
+		if r, c := errorStatus(docErr); r != reason || c != code {
+			return metav1.StatusReasonUnknown, 0
+		}
+	}
+	return reason, code
+}
+
+// documentErrors returns the errors of the documents that failed to be applied as generic objects
+func documentErrors(err *resources.ApplyError) []interface{} {
+	errs := make([]interface{}, 0, len(err.Errors))
+	for _, docErr := range err.Errors {
+		reason, code := errorStatus(docErr)
+		errs = append(errs, map[string]interface{}{
+			"index":   docErr.Index,
+			"kind":    docErr.Kind,
+			"name":    docErr.Name,
+			"reason":  string(reason),
+			"code":    code,
+			"message": docErr.Err.Error(),
+		})
+	}
+	return errs
+}
+
+// statusDetails returns the details of a status as a generic object
+func statusDetails(details *metav1.StatusDetails) map[string]interface{} {
+	causes := []interface{}{}
+	for _, cause := range details.Causes {
+		causes = append(causes, map[string]interface{}{
+			"reason":  string(cause.Type),
+			"message": cause.Message,
+			"field":   cause.Field,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":   details.Name,
+		"group":  details.Group,
+		"kind":   details.Kind,
+		"uid":    string(details.UID),
+		"causes": causes,
+	}
+}
+
+// withErrors returns an object with the properties of the given object, whose methods throw the errors
+// returned by the wrapped Go methods as errors created with newError. Helpers and clients returned by the
+// methods are also wrapped
+func withErrors(rt *sobek.Runtime, obj *sobek.Object) *sobek.Object {
+	wrapped := rt.NewObject()
+	for _, key := range obj.Keys() {
+		value := obj.Get(key)
+		method, isMethod := sobek.AssertFunction(value)
+		if !isMethod {
+			_ = wrapped.Set(key, value)
+			continue
+		}
+
+		_ = wrapped.Set(key, func(call sobek.FunctionCall) sobek.Value {
+			result, err := method(obj, call.Arguments...)
+			if err != nil {
+				goErr := errors.Unwrap(err)
+				if goErr == nil {
+					// exceptions not originated in Go errors are thrown as they are
+					panic(err)
+				}
+				panic(newError(rt, goErr))
+			}
+
+			switch result.Export().(type) {
+			case *Helpers, *Kubernetes:
+				return withErrors(rt, result.ToObject(rt))
+			}
+			return result
+		})
+	}
+
+	return wrapped
+}
+
+// hasReason returns a predicate that checks if an error thrown by the module has the given reason
+func hasReason(reason metav1.StatusReason) func(sobek.Value) bool {
+	return func(err sobek.Value) bool {
+		obj, isObject := err.(*sobek.Object)
+		if !isObject {
+			return false
+		}
+		value := obj.Get("reason")
+		return value != nil && value.String() == string(reason)
+	}
+}
+
+// errorPredicates returns the predicates for checking the reason of the errors thrown by the module
+func errorPredicates() map[string]interface{} {
+	return map[string]interface{}{
+		"isAlreadyExists":   hasReason(metav1.StatusReasonAlreadyExists),
+		"isBadRequest":      hasReason(metav1.StatusReasonBadRequest),
+		"isConflict":        hasReason(metav1.StatusReasonConflict),
+		"isForbidden":       hasReason(metav1.StatusReasonForbidden),
+		"isGone":            hasReason(metav1.StatusReasonGone),
+		"isInvalid":         hasReason(metav1.StatusReasonInvalid),
+		"isNotFound":        hasReason(metav1.StatusReasonNotFound),
+		"isServerTimeout":   hasReason(metav1.StatusReasonServerTimeout),
+		"isTimeout":         hasReason(metav1.StatusReasonTimeout),
+		"isTooManyRequests": hasReason(metav1.StatusReasonTooManyRequests),
+		"isUnauthorized":    hasReason(metav1.StatusReasonUnauthorized),
+	}
+}
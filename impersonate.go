@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"errors"
+	"net/http"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// isSet returns true if any of the fields of the impersonated identity is set
+func (i ImpersonateConfig) isSet() bool {
+	return i.User != "" || len(i.Groups) > 0 || i.UID != "" || len(i.Extra) > 0
+}
+
+// toImpersonationConfig returns the impersonation settings for the rest config
+func (i ImpersonateConfig) toImpersonationConfig() (rest.ImpersonationConfig, error) {
+	if i.User == "" {
+		return rest.ImpersonationConfig{}, errors.New("impersonating groups, uid or extra fields requires a user")
+	}
+
+	return rest.ImpersonationConfig{
+		UserName: i.User,
+		Groups:   i.Groups,
+		UID:      i.UID,
+		Extra:    i.Extra,
+	}, nil
+}
+
+// withImpersonation returns a http client that impersonates the given identity in the requests sent with
+// the given client. If no identity is given, the client is returned as is
+func withImpersonation(client *http.Client, impersonate rest.ImpersonationConfig) *http.Client {
+	if impersonate.UserName == "" && impersonate.UID == "" && len(impersonate.Groups) == 0 &&
+		len(impersonate.Extra) == 0 {
+		return client
+	}
+
+	impersonating := *client
+	impersonating.Transport = transport.NewImpersonatingRoundTripper(
+		transport.ImpersonationConfig{
+			UserName: impersonate.UserName,
+			UID:      impersonate.UID,
+			Groups:   impersonate.Groups,
+			Extra:    impersonate.Extra,
+		},
+		client.Transport,
+	)
+
+	return &impersonating
+}
+
+// impersonate returns clients that impersonate the given identity. The clients share the transport, the rate
+// limiter, the RESTMapper and the discovery cache with these clients
+func (s *sharedClients) impersonate(impersonate ImpersonateConfig) (*sharedClients, error) {
+	impersonation, err := impersonate.toImpersonationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// injected clients for unit testing have no transport to impersonate with
+	if s.httpClient == nil {
+		return nil, errors.New("impersonation is only supported by clients created from a configuration")
+	}
+
+	config := *s.config
+	derived := *s
+	derived.config = &config
+
+	config.rest = rest.CopyConfig(s.config.rest)
+	config.rest.Impersonate = impersonation
+	httpClient := withImpersonation(s.httpClient, impersonation)
+
+	derived.clientset, err = kubernetes.NewForConfigAndClient(config.rest, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	derived.dynamic, err = dynamic.NewForConfigAndClient(config.rest, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &derived, nil
+}
+
+// As returns a client that impersonates the given user and groups. The client shares the transport, the rate
+// limiter and the discovery cache with this client. The identity impersonated by this client, if any, is
+// replaced and not combined with the given one
+func (k *Kubernetes) As(user string, groups []string) (*Kubernetes, error) {
+	clients, err := k.clients.impersonate(ImpersonateConfig{User: user, Groups: groups})
+	if err != nil {
+		return nil, err
+	}
+
+	return newKubernetes(k.ctx, k.vu, clients)
+}
@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/transport"
+)
+
+func TestImpersonation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		headers http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"PodList","apiVersion":"v1","items":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	lastHeaders := func() http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return headers
+	}
+
+	clients, err := newSharedClients(KubeConfig{
+		Server:      server.URL,
+		Token:       "token",
+		Impersonate: ImpersonateConfig{User: "alice", Groups: []string{"tenant-a"}},
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = clients.clientset.CoreV1().Pods("testns").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "alice", lastHeaders().Get(transport.ImpersonateUserHeader))
+	require.Equal(t, []string{"tenant-a"}, lastHeaders().Values(transport.ImpersonateGroupHeader))
+
+	derived, err := clients.impersonate(ImpersonateConfig{User: "bob"})
+	require.NoError(t, err)
+	require.Same(t, clients.httpClient, derived.httpClient)
+	require.Equal(t, "bob", derived.config.rest.Impersonate.UserName)
+	require.Equal(t, "alice", clients.config.rest.Impersonate.UserName)
+
+	_, err = derived.dynamic.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("testns").List(
+		context.Background(), metav1.ListOptions{},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "bob", lastHeaders().Get(transport.ImpersonateUserHeader))
+	require.Empty(t, lastHeaders().Values(transport.ImpersonateGroupHeader))
+
+	_, err = clients.impersonate(ImpersonateConfig{Groups: []string{"tenant-a"}})
+	require.Error(t, err)
+
+	_, err = newSharedClients(KubeConfig{
+		Server:      server.URL,
+		Token:       "token",
+		Impersonate: ImpersonateConfig{UID: "1234"},
+	}, nil)
+	require.Error(t, err)
+}
+
+func TestImpersonationOfInjectedClients(t *testing.T) {
+	t.Parallel()
+
+	clients := &sharedClients{config: &clientConfig{}}
+
+	_, err := clients.impersonate(ImpersonateConfig{User: "alice"})
+	require.Error(t, err)
+}
@@ -2,7 +2,7 @@
 package testutils
 
 import (
-	"fmt"
+	"strings"
 
 	k8s "k8s.io/client-go/kubernetes"
 
@@ -34,10 +34,12 @@ type FakeRESTMapper struct {
 	meta.RESTMapper
 }
 
-// RESTMapping provides information needed to deal with supported REST resources
-func (f *FakeRESTMapper) RESTMapping(gk schema.GroupKind, _ ...string) (*meta.RESTMapping, error) {
-	kindMapping := map[string]schema.GroupVersionResource{
+// fakeKinds returns the kinds known by the FakeRESTMapper and their resources
+func fakeKinds() map[string]schema.GroupVersionResource {
+	return map[string]schema.GroupVersionResource{
 		"ConfigMap":             {Group: "", Version: "v1", Resource: "configmaps"},
+		"ControllerRevision":    {Group: "apps", Version: "v1", Resource: "controllerrevisions"},
+		"DaemonSet":             {Group: "apps", Version: "v1", Resource: "daemonsets"},
 		"Deployment":            {Group: "apps", Version: "v1", Resource: "deployments"},
 		"Endpoints":             {Group: "", Version: "v1", Resource: "endpoints"},
 		"Ingress":               {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
@@ -45,19 +47,37 @@ func (f *FakeRESTMapper) RESTMapping(gk schema.GroupKind, _ ...string) (*meta.RE
 		"PersistentVolume":      {Group: "", Version: "v1", Resource: "persistentvolumes"},
 		"PersistentVolumeClaim": {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
 		"Pod":                   {Group: "", Version: "v1", Resource: "pods"},
+		"ReplicaSet":            {Group: "apps", Version: "v1", Resource: "replicasets"},
 		"Namespace":             {Group: "", Version: "v1", Resource: "namespaces"},
 		"Node":                  {Group: "", Version: "v1", Resource: "nodes"},
 		"Secret":                {Group: "", Version: "v1", Resource: "secrets"},
 		"Service":               {Group: "", Version: "v1", Resource: "services"},
 		"StatefulSet":           {Group: "apps", Version: "v1", Resource: "statefulsets"},
+
+		"CustomResourceDefinition": {Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
 	}
+}
 
+// fakeShortNames returns the short names of the resources known by the FakeRESTMapper
+func fakeShortNames() map[string]string {
+	return map[string]string{
+		"cm":     "configmaps",
+		"deploy": "deployments",
+		"ns":     "namespaces",
+		"po":     "pods",
+		"svc":    "services",
+	}
+}
+
+// RESTMapping provides information needed to deal with supported REST resources
+func (f *FakeRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	kindMapping := fakeKinds()
 	gvr, found := kindMapping[gk.Kind]
 	if !found {
-		return nil, fmt.Errorf("unknown kind: '%s'", gk.Kind)
+		return nil, &meta.NoKindMatchError{GroupKind: gk, SearchedVersions: versions}
 	}
 	scope := meta.RESTScopeNamespace
-	if gk.Kind == "Namespace" || gk.Kind == "Node" {
+	if gk.Kind == "Namespace" || gk.Kind == "Node" || gk.Kind == "CustomResourceDefinition" {
 		scope = meta.RESTScopeRoot
 	}
 
@@ -67,3 +87,22 @@ func (f *FakeRESTMapper) RESTMapping(gk schema.GroupKind, _ ...string) (*meta.RE
 		Scope:            scope,
 	}, nil
 }
+
+// KindFor returns the kind of a resource given by its plural, singular or short name
+func (f *FakeRESTMapper) KindFor(resource schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	name := strings.ToLower(resource.Resource)
+	if plural, found := fakeShortNames()[name]; found {
+		name = plural
+	}
+
+	for kind, gvr := range fakeKinds() {
+		if name == gvr.Resource || name == strings.ToLower(kind) {
+			if resource.Group != "" && resource.Group != gvr.Group {
+				continue
+			}
+			return gvr.GroupVersion().WithKind(kind), nil
+		}
+	}
+
+	return schema.GroupVersionKind{}, &meta.NoResourceMatchError{PartialResource: resource}
+}
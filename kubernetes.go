@@ -3,24 +3,20 @@ package kubernetes
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"path/filepath"
 
 	"github.com/grafana/sobek"
 	"go.k6.io/k6/v2/js/common"
-	"k8s.io/client-go/rest"
 
 	"github.com/grafana/xk6-kubernetes/pkg/api"
+	"github.com/grafana/xk6-kubernetes/pkg/helpers"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
 
 	"go.k6.io/k6/v2/js/modules"
 	"k8s.io/apimachinery/pkg/api/meta"
-	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // Required for access to GKE and AKS
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 )
 
 func init() {
@@ -29,32 +25,57 @@ func init() {
 
 // RootModule is the global module object type. It is instantiated once per test
 // run and will be used to create `k6/x/kubernetes` module instances for each VU.
-type RootModule struct{}
+type RootModule struct {
+	// cache holds the clients shared by the VUs
+	cache clientCache
+}
 
 // ModuleInstance represents an instance of the JS module.
 type ModuleInstance struct {
-	vu modules.VU
+	vu   modules.VU
+	root *RootModule
 	// clientset enables injection of a pre-configured Kubernetes environment for unit tests
 	clientset kubernetes.Interface
 	// dynamic enables injection of a fake dynamic client for unit tests
 	dynamic dynamic.Interface
 	// mapper enables injection of a fake RESTMapper for unit tests
 	mapper meta.RESTMapper
+	// metrics reported for the requests sent to the Kubernetes API server
+	metrics *kubernetesMetrics
 }
 
 // Kubernetes is the exported object used within JavaScript.
 type Kubernetes struct {
 	api.Kubernetes
-	client      kubernetes.Interface
-	metaOptions metaV1.ListOptions
-	ctx         context.Context
+	// APIResources returns the resources served by the cluster. It shadows the method of api.Kubernetes, whose
+	// name would be mapped to aPIResources in JavaScript
+	APIResources func(options ...api.APIResourcesOptions) ([]api.APIResource, error) `js:"apiResources"`
+	// APIResourcesAsync is the asynchronous version of APIResources
+	APIResourcesAsync func(options ...api.APIResourcesOptions) *sobek.Promise `js:"apiResourcesAsync"`
+	// Cluster describes the cluster the client is connected to
+	Cluster ClusterInfo `js:"cluster"`
+	// Namespace is the default namespace of the client
+	Namespace string `js:"namespace"`
+	client    kubernetes.Interface
+	// clients are the clients shared with other VUs, from which clients impersonating other identities
+	// are derived
+	clients *sharedClients
+	ctx     context.Context
+	vu      modules.VU
 }
 
-// KubeConfig represents the initialization settings for the kubernetes api client.
-type KubeConfig struct {
-	ConfigPath string
-	Server     string
-	Token      string
+// Helpers is the exported object used within JavaScript for accessing the helpers
+type Helpers struct {
+	helpers.Helpers
+	vu modules.VU
+}
+
+// Helpers returns the helpers for the given namespace. If none is specified, the default namespace is used
+func (k *Kubernetes) Helpers(namespace string) *Helpers {
+	return &Helpers{
+		Helpers: k.Kubernetes.Helpers(namespace),
+		vu:      k.vu,
+	}
 }
 
 // Ensure the interfaces are implemented correctly.
@@ -65,19 +86,37 @@ var (
 
 // NewModuleInstance implements the modules.Module interface to return
 // a new instance for each VU.
-func (*RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
-	return &ModuleInstance{
-		vu: vu,
+func (r *RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
+	mi := &ModuleInstance{
+		vu:   vu,
+		root: r,
 	}
+
+	if env := vu.InitEnv(); env != nil && env.Registry != nil {
+		m, err := registerMetrics(env.Registry)
+		if err != nil {
+			common.Throw(vu.Runtime(), err)
+		}
+		mi.metrics = m
+	}
+
+	return mi
 }
 
 // Exports implements the modules.Instance interface and returns the exports
 // of the JS module.
 func (mi *ModuleInstance) Exports() modules.Exports {
+	rt := mi.vu.Runtime()
+	constructor := rt.ToValue(mi.newClient).ToObject(rt)
+	if err := constructor.Set("contexts", getContexts); err != nil {
+		common.Throw(rt, err)
+	}
+
+	named := errorPredicates()
+	named["Kubernetes"] = constructor
+
 	return modules.Exports{
-		Named: map[string]interface{}{
-			"Kubernetes": mi.newClient,
-		},
+		Named: named,
 	}
 }
 
@@ -85,93 +124,89 @@ func (mi *ModuleInstance) newClient(c sobek.ConstructorCall) *sobek.Object {
 	rt := mi.vu.Runtime()
 	ctx := mi.vu.Context()
 
-	obj := &Kubernetes{}
-	var config *rest.Config
+	var options KubeConfig
+	err := rt.ExportTo(c.Argument(0), &options)
+	if err != nil {
+		common.Throw(rt,
+			fmt.Errorf("Kubernetes constructor expects KubeConfig as it's argument: %w", err))
+	}
 
-	// if clientset was not injected for unit testing
+	var (
+		clients  *sharedClients
+		cacheHit bool
+	)
+	// if clients were not injected for unit testing
 	if mi.clientset == nil {
-		var options KubeConfig
-		err := rt.ExportTo(c.Argument(0), &options)
-		if err != nil {
-			common.Throw(rt,
-				fmt.Errorf("Kubernetes constructor expects KubeConfig as it's argument: %w", err))
-		}
-		config, err = getClientConfig(options)
+		clients, cacheHit, err = mi.getClients(options)
 		if err != nil {
 			common.Throw(rt, err)
 		}
-		clientset, err := kubernetes.NewForConfig(config)
+		clients, err = clients.forVU()
 		if err != nil {
 			common.Throw(rt, err)
 		}
-		obj.client = clientset
 	} else {
-		// Pre-configured clientset is being injected for unit testing
-		obj.client = mi.clientset
-	}
-
-	// If dynamic client was not injected for unit testing
-	// It is assumed rest config is set
-	if mi.dynamic == nil {
-		k8s, err := api.NewFromConfig(
-			api.KubernetesConfig{
-				Clientset: obj.client,
-				Config:    config,
-				Context:   ctx,
-			},
-		)
-		if err != nil {
-			common.Throw(rt, err)
+		// Pre-configured clientset, dynamic client and RESTMapper are injected for unit testing
+		namespace := resources.DefaultNamespace
+		if options.Namespace != "" {
+			namespace = options.Namespace
 		}
-		obj.Kubernetes = k8s
-	} else {
-		// Pre-configured dynamic client and RESTMapper are injected for unit testing
-		k8s, err := api.NewFromConfig(
-			api.KubernetesConfig{
-				Clientset: obj.client,
-				Client:    mi.dynamic,
-				Mapper:    mi.mapper,
-				Context:   ctx,
-			},
-		)
-		if err != nil {
-			common.Throw(rt, err)
+		clients = &sharedClients{
+			config:    &clientConfig{namespace: namespace},
+			clientset: mi.clientset,
+			dynamic:   mi.dynamic,
+			mapper:    mi.mapper,
 		}
-		obj.Kubernetes = k8s
 	}
 
-	obj.metaOptions = metaV1.ListOptions{}
-	obj.ctx = ctx
+	// the scope identifies the VU in the requests sent with the shared clients
+	scope := &clientScope{vu: mi.vu, cacheHit: cacheHit}
+	scope.cacheReported.Store(options.DisableCache)
 
-	return rt.ToValue(obj).ToObject(rt)
+	obj, err := newKubernetes(withClientScope(ctx, scope), mi.vu, clients)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+
+	return withErrors(rt, rt.ToValue(obj).ToObject(rt))
 }
 
-func getClientConfig(options KubeConfig) (*rest.Config, error) {
-	// If server and token are provided, use them
-	if options.Server != "" && options.Token != "" {
-		return &rest.Config{
-			Host:        options.Server,
-			BearerToken: options.Token,
-			TLSClientConfig: rest.TLSClientConfig{
-				Insecure: true,
-			},
-		}, nil
+// newKubernetes returns the object used within JavaScript for the shared clients
+func newKubernetes(ctx context.Context, vu modules.VU, clients *sharedClients) (*Kubernetes, error) {
+	k8s, err := api.NewFromConfig(api.KubernetesConfig{
+		Clientset: clients.clientset,
+		Client:    clients.dynamic,
+		Mapper:    clients.mapper,
+		Discovery: clients.discovery,
+		Config:    clients.config.rest,
+		Context:   ctx,
+		Namespace: clients.config.namespace,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// If server and token are not provided, use kubeconfig
-	kubeconfig := options.ConfigPath
-	if kubeconfig == "" {
-		// are we in-cluster?
-		config, err := rest.InClusterConfig()
-		if err == nil {
-			return config, nil
-		}
-		// we aren't in-cluster
-		home := homedir.HomeDir()
-		if home == "" {
-			return nil, errors.New("home directory not found")
-		}
-		kubeconfig = filepath.Join(home, ".kube", "config")
+	k := &Kubernetes{
+		Kubernetes:   k8s,
+		APIResources: k8s.APIResources,
+		Cluster:      clients.config.cluster,
+		Namespace:    clients.config.namespace,
+		client:       clients.clientset,
+		clients:      clients,
+		ctx:          ctx,
+		vu:           vu,
+	}
+	k.APIResourcesAsync = k.apiResourcesAsync
+
+	return k, nil
+}
+
+// getClients returns the clients for the options. Unless disabled in the options, the clients are cached
+// in the root module and shared by the VUs. Returns true if the clients were cached
+func (mi *ModuleInstance) getClients(options KubeConfig) (*sharedClients, bool, error) {
+	if options.DisableCache || mi.root == nil {
+		clients, err := newSharedClients(options, mi.metrics)
+		return clients, false, err
 	}
-	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	return mi.root.cache.get(options, mi.metrics)
 }
@@ -2,7 +2,12 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
 	"testing"
 
 	"github.com/grafana/sobek"
@@ -13,11 +18,24 @@ import (
 	"go.k6.io/k6/v2/js/modulestest"
 	"go.k6.io/k6/v2/lib"
 	"go.k6.io/k6/v2/metrics"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8stest "k8s.io/client-go/testing"
+	"k8s.io/client-go/transport"
 )
 
 // setupTestEnv should be called from each test to build the execution environment for the test
 func setupTestEnv(t *testing.T, objs ...runtime.Object) *sobek.Runtime {
+	rt, m := setupTestModule(t)
+	injectFakes(t, m, objs...)
+
+	return rt
+}
+
+// setupTestModule builds the execution environment for tests that use clients created from the options
+// given in the script instead of fake clients
+func setupTestModule(t *testing.T) (*sobek.Runtime, *ModuleInstance) {
 	rt := sobek.New()
 	rt.SetFieldNameMapper(common.FieldNameMapper{})
 
@@ -36,7 +54,6 @@ func setupTestEnv(t *testing.T, objs ...runtime.Object) *sobek.Runtime {
 	m, ok := root.NewModuleInstance(
 		&modulestest.VU{
 			RuntimeField: rt,
-			InitEnvField: &common.InitEnvironment{},
 			CtxField:     context.Background(),
 			StateField:   state,
 		},
@@ -44,16 +61,52 @@ func setupTestEnv(t *testing.T, objs ...runtime.Object) *sobek.Runtime {
 	require.True(t, ok)
 	require.NoError(t, rt.Set("Kubernetes", m.Exports().Named["Kubernetes"]))
 
+	return rt, m
+}
+
+// setupTestEnvWithEventLoop builds the execution environment for tests that require the event loop
+func setupTestEnvWithEventLoop(t *testing.T, objs ...runtime.Object) *modulestest.Runtime {
+	env := modulestest.NewRuntime(t)
+
+	testLog := logrus.New()
+	testLog.SetOutput(io.Discard)
+	env.MoveToVUContext(&lib.State{
+		Options: lib.Options{
+			SystemTags: metrics.NewSystemTagSet(metrics.TagVU),
+		},
+		Logger: testLog,
+		Tags:   lib.NewVUStateTags(metrics.NewRegistry().RootTagSet()),
+	})
+
+	root := &RootModule{}
+	m, ok := root.NewModuleInstance(env.VU).(*ModuleInstance)
+	require.True(t, ok)
+	require.NoError(t, env.VU.Runtime().Set("Kubernetes", m.Exports().Named["Kubernetes"]))
+
+	injectFakes(t, m, objs...)
+
+	return env
+}
+
+// injectFakes injects fake clients in the module instance
+func injectFakes(t *testing.T, m *ModuleInstance, objs ...runtime.Object) {
 	m.clientset = localutils.NewFakeClientset(objs...)
 
 	dynamic, err := localutils.NewFakeDynamic()
 	if err != nil {
 		t.Errorf("unexpected error creating fake client %v", err)
 	}
+	// the fake client does not support server-side apply, so the applied objects are returned as they are
+	dynamic.PrependReactor("patch", "*", func(action k8stest.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stest.PatchActionImpl)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		return true, obj, obj.UnmarshalJSON(patch.GetPatch())
+	})
 	m.dynamic = dynamic
 	m.mapper = &localutils.FakeRESTMapper{}
-
-	return rt
 }
 
 // TestGenericApiIsScriptable runs through creating, getting, listing and deleting an object
@@ -146,3 +199,646 @@ if (!helpers.waitPodRunning(pod.metadata.name, 5)) {
 `)
 	require.NoError(t, err)
 }
+
+// TestListOptionsAreScriptable lists objects using selectors and pagination
+func TestListOptionsAreScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+for (const name of ["busybox", "nginx"]) {
+	k8s.create({
+		apiVersion: "v1",
+		kind:       "Pod",
+		metadata: {
+			name:      name,
+			namespace: "testns",
+			labels:    { app: name }
+		}
+	})
+}
+
+const pods = k8s.list("Pod", "testns", { labelSelector: "app=nginx" })
+if (pods.length != 1 || pods[0].metadata.name != "nginx") {
+	throw new Error("Expected listing with only the nginx Pod")
+}
+
+const page = k8s.listPage("Pod", "testns", { limit: 10 })
+if (page.items.length != 2 || page.continue !== "") {
+	throw new Error("Expected a single page with 2 Pods")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestPatchIsScriptable patches an object using different types of patches
+func TestPatchIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+k8s.create({
+	apiVersion: "v1",
+	kind:       "Pod",
+	metadata: {
+		name:      "busybox",
+		namespace: "testns"
+	}
+})
+
+let pod = k8s.patch("Pod", "busybox", "testns", { metadata: { labels: { app: "busybox" } } })
+if (pod.metadata.labels.app != "busybox") {
+	throw new Error("Merge patch failed to add label")
+}
+
+pod = k8s.patch("Pod", "busybox", "testns", [
+	{ op: "replace", path: "/metadata/labels/app", value: "nginx" }
+], { type: "json" })
+if (pod.metadata.labels.app != "nginx") {
+	throw new Error("JSON patch failed to replace label")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestDeleteOptionsAreScriptable deletes an object with options and waits for it to be gone
+func TestDeleteOptionsAreScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+k8s.create({
+	apiVersion: "v1",
+	kind:       "Pod",
+	metadata: {
+		name:      "busybox",
+		namespace: "testns"
+	}
+})
+
+k8s.delete("Pod", "busybox", "testns", { gracePeriodSeconds: 0, propagationPolicy: "Background", wait: 5 })
+if (k8s.list("Pod", "testns").length != 0) {
+	throw new Error("Deletion failed to remove pod")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestWatchIsScriptable watches the events of an object until the watch is stopped
+func TestWatchIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	env := setupTestEnvWithEventLoop(t)
+
+	_, err := env.RunOnEventLoop(`
+const k8s = new Kubernetes()
+
+const pod = {
+	apiVersion: "v1",
+	kind:       "Pod",
+	metadata: {
+		name:      "busybox",
+		namespace: "testns"
+	}
+}
+
+var events = []
+const watch = k8s.watch("Pod", "testns", {}, function(event) {
+	events.push(event.type)
+	if (event.type == "DELETED") {
+		watch.stop()
+	}
+})
+
+k8s.create(pod)
+k8s.delete("Pod", pod.metadata.name, pod.metadata.namespace)
+`)
+	require.NoError(t, err)
+
+	events, err := env.VU.Runtime().RunString(`events.join(",")`)
+	require.NoError(t, err)
+	require.Equal(t, "ADDED,DELETED", events.String())
+}
+
+// TestAsyncIsScriptable runs operations and helpers concurrently using promises
+func TestAsyncIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	env := setupTestEnvWithEventLoop(t)
+
+	_, err := env.RunOnEventLoop(`
+const k8s = new Kubernetes()
+
+function podSpec(name) {
+	return {
+		apiVersion: "v1",
+		kind:       "Pod",
+		metadata: {
+			name:      name,
+			namespace: "default"
+		},
+		status: {
+			phase: "Running"
+		}
+	}
+}
+
+var results = []
+async function run() {
+	await Promise.all([k8s.createAsync(podSpec("busybox")), k8s.createAsync(podSpec("nginx"))])
+
+	const helpers = k8s.helpers()
+	const running = await Promise.all([
+		helpers.waitPodRunningAsync("busybox", 5),
+		helpers.waitPodRunningAsync("nginx", 5)
+	])
+	if (!running.every((r) => r)) {
+		throw new Error("should not timeout")
+	}
+
+	const pods = await k8s.listAsync("Pod", "default")
+	results.push(pods.length)
+
+	try {
+		await k8s.getAsync("Pod", "unknown", "default")
+	} catch (e) {
+		results.push(e.reason)
+	}
+}
+
+run().catch((e) => results.push(e.toString()))
+`)
+	require.NoError(t, err)
+
+	results, err := env.VU.Runtime().RunString(`results.join(",")`)
+	require.NoError(t, err)
+	require.Equal(t, "2,NotFound", results.String())
+}
+
+// TestErrorsAreTyped checks the errors thrown have the reason and code returned by the server
+func TestErrorsAreTyped(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+	require.NoError(t, rt.Set("isNotFound", errorPredicates()["isNotFound"]))
+	require.NoError(t, rt.Set("isAlreadyExists", errorPredicates()["isAlreadyExists"]))
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+try {
+	k8s.get("Pod", "unknown", "testns")
+	throw new Error("expected an error")
+} catch (e) {
+	if (!isNotFound(e) || isAlreadyExists(e) || e.code != 404 || e.details.name != "unknown") {
+		throw new Error("unexpected error: " + e.reason + " " + e.code)
+	}
+}
+
+const pod = {
+	apiVersion: "v1",
+	kind:       "Pod",
+	metadata: {
+		name:      "busybox",
+		namespace: "testns"
+	}
+}
+k8s.create(pod)
+try {
+	k8s.create(pod)
+	throw new Error("expected an error")
+} catch (e) {
+	if (!isAlreadyExists(e) || e.code != 409) {
+		throw new Error("unexpected error: " + e.reason + " " + e.code)
+	}
+}
+
+try {
+	k8s.list("Pod", "testns", "invalid")
+	throw new Error("expected an error")
+} catch (e) {
+	if (!(e instanceof TypeError)) {
+		throw new Error("unexpected error: " + e)
+	}
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestClusterIsScriptable checks the cluster of the client and the contexts function are exposed
+func TestClusterIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+if (typeof Kubernetes.contexts !== "function") {
+	throw new Error("Expected contexts function in Kubernetes")
+}
+
+const k8s = new Kubernetes()
+if (k8s.cluster === undefined || k8s.cluster.server !== "") {
+	throw new Error("Expected empty cluster for injected clients")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestNamespaceIsScriptable creates objects in the default namespace of the client
+func TestNamespaceIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+if (new Kubernetes().namespace !== "default") {
+	throw new Error("Expected default namespace")
+}
+
+const k8s = new Kubernetes({ namespace: "testns" })
+if (k8s.namespace !== "testns") {
+	throw new Error("Expected namespace from options")
+}
+
+k8s.create({
+	apiVersion: "v1",
+	kind:       "Pod",
+	metadata: {
+		name: "busybox"
+	}
+})
+
+const pod = k8s.get("Pod", "busybox", "testns")
+if (pod.metadata.namespace !== "testns") {
+	throw new Error("Expected pod created in the namespace of the client")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestDiscoveryIsScriptable checks the discovery operations are exposed
+func TestDiscoveryIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+if (!k8s.hasKind("Deployment.apps") || k8s.hasKind("Gateway.gateway.networking.k8s.io")) {
+	throw new Error("Unexpected kinds served by the cluster")
+}
+
+if (typeof k8s.serverVersion().major !== "number") {
+	throw new Error("Expected numeric major version")
+}
+
+if (!Array.isArray(k8s.apiResources({ group: "apps" }))) {
+	throw new Error("Expected list of API resources")
+}
+
+if (k8s.aPIResources !== undefined || k8s.aPIResourcesAsync !== undefined) {
+	throw new Error("Expected API resources to be exposed only as apiResources")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestDiscoveryAsyncIsScriptable runs the discovery operations using promises
+func TestDiscoveryAsyncIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	env := setupTestEnvWithEventLoop(t)
+
+	_, err := env.RunOnEventLoop(`
+const k8s = new Kubernetes()
+
+var results = []
+async function run() {
+	results.push(await k8s.hasKindAsync("Deployment.apps"))
+	results.push(await k8s.hasKindAsync("Gateway.gateway.networking.k8s.io"))
+	results.push(typeof (await k8s.serverVersionAsync()).major)
+	results.push(Array.isArray(await k8s.apiResourcesAsync({ group: "apps" })))
+	results.push(await k8s.refreshDiscoveryAsync())
+}
+
+run().catch((e) => results.push(e.toString()))
+`)
+	require.NoError(t, err)
+
+	results, err := env.VU.Runtime().RunString(`results.join(",")`)
+	require.NoError(t, err)
+	require.Equal(t, "true,false,number,true,false", results.String())
+}
+
+// TestImpersonationIsScriptable checks the clients impersonating other identities send their requests
+// as the impersonated identity
+func TestImpersonationIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		headers = map[string]http.Header{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api":
+			_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		case "/apis":
+			_, _ = w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`))
+		case "/api/v1":
+			_, _ = w.Write([]byte(`{"kind":"APIResourceList","groupVersion":"v1","resources":[` +
+				`{"name":"pods","singularName":"pod","namespaced":true,"kind":"Pod","verbs":["get"]}]}`))
+		default:
+			name := path.Base(r.URL.Path)
+			mu.Lock()
+			headers[name] = r.Header.Clone()
+			mu.Unlock()
+			_, _ = fmt.Fprintf(w, `{"kind":"Pod","apiVersion":"v1","metadata":{"name":%q,"namespace":"testns"}}`, name)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	rt, _ := setupTestModule(t)
+	require.NoError(t, rt.Set("server", server.URL))
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes({ server: server, token: "token", namespace: "testns", disableCache: true })
+
+const tenant = k8s.as("alice", ["tenant-a"])
+if (tenant.namespace !== "testns") {
+	throw new Error("Expected namespace of the client")
+}
+if (tenant.helpers().getExternalIP === undefined) {
+	throw new Error("Expected helpers of the client")
+}
+
+tenant.get("Pod", "impersonated", "testns")
+k8s.get("Pod", "authenticated", "testns")
+`)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, "alice", headers["impersonated"].Get(transport.ImpersonateUserHeader))
+	require.Equal(t, []string{"tenant-a"}, headers["impersonated"].Values(transport.ImpersonateGroupHeader))
+	require.Empty(t, headers["authenticated"].Get(transport.ImpersonateUserHeader))
+}
+
+// TestAuthorizationIsScriptable checks the authorization checks are exposed
+func TestAuthorizationIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+if (k8s.canI("get", "Pod", { namespace: "testns" }).allowed !== false) {
+	throw new Error("Expected access not allowed by fake clientset")
+}
+
+if (typeof k8s.subjectAccessReview("alice", ["tenant-a"], "get", "Pod").denied !== "boolean") {
+	throw new Error("Expected access review result")
+}
+
+if (!Array.isArray(k8s.rulesFor("testns").resourceRules)) {
+	throw new Error("Expected resource rules")
+}
+`)
+	require.NoError(t, err)
+}
+
+// TestAuthorizationAsyncIsScriptable runs the authorization checks using promises
+func TestAuthorizationAsyncIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	env := setupTestEnvWithEventLoop(t)
+
+	_, err := env.RunOnEventLoop(`
+const k8s = new Kubernetes()
+
+var results = []
+async function run() {
+	results.push((await k8s.canIAsync("list", "Pod", { allNamespaces: true })).allowed)
+	results.push(typeof (await k8s.subjectAccessReviewAsync("alice", ["tenant-a"], "get", "Pod")).denied)
+	results.push(Array.isArray((await k8s.rulesForAsync("testns")).resourceRules))
+}
+
+run().catch((e) => results.push(e.toString()))
+`)
+	require.NoError(t, err)
+
+	results, err := env.VU.Runtime().RunString(`results.join(",")`)
+	require.NoError(t, err)
+	require.Equal(t, "false,boolean,true", results.String())
+}
+
+// TestWaitForIsScriptable waits for objects with conditions, JSONPath expressions and predicates
+func TestWaitForIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+k8s.create({
+	apiVersion: "v1",
+	kind:       "PersistentVolumeClaim",
+	metadata: {
+		name: "data"
+	},
+	status: {
+		phase: "Bound",
+		conditions: [{ type: "Resizing", status: "False" }]
+	}
+})
+
+const helpers = k8s.helpers()
+
+const pvc = helpers.waitFor("PersistentVolumeClaim", "data", { jsonpath: "{.status.phase}", equals: "Bound" })
+if (pvc.metadata.name !== "data") {
+	throw new Error("Expected the matched object")
+}
+
+helpers.waitFor("persistentvolumeclaims", "data", {
+	condition: "Resizing=False",
+	predicate: (obj) => obj.status.phase === "Bound",
+	timeout: 5
+})
+
+try {
+	helpers.waitFor("PersistentVolumeClaim", "data", {
+		predicate: (obj) => { throw new Error("predicate failed") },
+	})
+	throw new Error("Expected error thrown by the predicate")
+} catch (e) {
+	if (!e.message.includes("predicate failed")) {
+		throw e
+	}
+}
+`)
+	require.NoError(t, err)
+}
+
+func TestWaitRolloutCompleteIsScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+k8s.create({
+	apiVersion: "apps/v1",
+	kind:       "Deployment",
+	metadata: {
+		name: "nginx",
+		generation: 1
+	},
+	spec: {
+		replicas: 2
+	},
+	status: {
+		observedGeneration: 1,
+		replicas: 2,
+		updatedReplicas: 2,
+		availableReplicas: 2
+	}
+})
+
+const helpers = k8s.helpers()
+
+if (!helpers.waitRolloutComplete("Deployment", "nginx", 5)) {
+	throw new Error("Expected the rollout to be complete")
+}
+
+try {
+	helpers.waitRolloutComplete("Pod", "nginx", 5)
+	throw new Error("Expected error for an unsupported kind")
+} catch (e) {
+	if (!e.message.includes("not supported")) {
+		throw e
+	}
+}
+`)
+	require.NoError(t, err)
+}
+
+func TestRolloutActionsAreScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+k8s.create({
+	apiVersion: "apps/v1",
+	kind:       "Deployment",
+	metadata: {
+		name: "nginx",
+		uid: "nginx-uid"
+	},
+	spec: {
+		selector: { matchLabels: { app: "nginx" } },
+		template: {
+			metadata: { labels: { app: "nginx" } },
+			spec: { containers: [{ name: "nginx", image: "nginx:1.27" }] }
+		}
+	}
+})
+
+k8s.create({
+	apiVersion: "apps/v1",
+	kind:       "ReplicaSet",
+	metadata: {
+		name: "nginx-1",
+		labels: { app: "nginx" },
+		annotations: { "deployment.kubernetes.io/revision": "1" },
+		ownerReferences: [{ apiVersion: "apps/v1", kind: "Deployment", name: "nginx", uid: "nginx-uid", controller: true }]
+	},
+	spec: {
+		template: {
+			metadata: { labels: { app: "nginx", "pod-template-hash": "1" } },
+			spec: { containers: [{ name: "nginx", image: "nginx:1.27" }] }
+		}
+	}
+})
+
+const helpers = k8s.helpers()
+
+const restarted = helpers.rolloutRestart("Deployment", "nginx")
+if (!restarted.spec.template.metadata.annotations["kubectl.kubernetes.io/restartedAt"]) {
+	throw new Error("Expected restartedAt annotation in the pod template")
+}
+
+if (!helpers.rolloutPause("deploy", "nginx").spec.paused) {
+	throw new Error("Expected deployment to be paused")
+}
+if (helpers.rolloutResume("deploy", "nginx").spec.paused) {
+	throw new Error("Expected deployment to be resumed")
+}
+
+const history = helpers.rolloutHistory("Deployment", "nginx")
+if (history.length !== 1 || history[0].revision !== 1 || history[0].name !== "nginx-1") {
+	throw new Error("Unexpected history: " + JSON.stringify(history))
+}
+
+const undone = helpers.rolloutUndo("Deployment", "nginx", 1)
+if (undone.spec.template.metadata.labels["pod-template-hash"]) {
+	throw new Error("Expected pod-template-hash label to be removed")
+}
+`)
+	require.NoError(t, err)
+}
+
+func TestApplyErrorsAreScriptable(t *testing.T) {
+	t.Parallel()
+
+	rt := setupTestEnv(t)
+
+	_, err := rt.RunString(`
+const k8s = new Kubernetes()
+
+const manifest = ` + "`" + `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: Unknown
+metadata:
+  name: unknown
+` + "`" + `
+
+try {
+	k8s.apply(manifest)
+	throw new Error("Expected error applying unknown kind")
+} catch (e) {
+	if (!e.applied) {
+		throw e
+	}
+	if (e.applied.length !== 1 || e.applied[0].metadata.name !== "config") {
+		throw new Error("Expected applied config map but got " + JSON.stringify(e.applied))
+	}
+	if (e.errors.length !== 1 || e.errors[0].index !== 1 || e.errors[0].kind !== "Unknown" ||
+		e.errors[0].name !== "unknown" || !e.errors[0].message) {
+		throw new Error("Unexpected document errors " + JSON.stringify(e.errors))
+	}
+}
+`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,350 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.k6.io/k6/v2/js/modules"
+	"go.k6.io/k6/v2/lib"
+	"go.k6.io/k6/v2/metrics"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// kubernetesMetrics holds the custom metrics reported by the module
+type kubernetesMetrics struct {
+	// duration of the requests to the Kubernetes API server
+	RequestDuration *metrics.Metric
+	// number of requests to the Kubernetes API server
+	Requests *metrics.Metric
+	// rate of failed requests to the Kubernetes API server
+	RequestFailed *metrics.Metric
+	// time spent waiting in the client-side rate limiter before sending requests
+	ThrottleWait *metrics.Metric
+	// rate of clients that reused the cached clients for their configuration
+	ClientCacheHits *metrics.Metric
+}
+
+// registerMetrics registers the custom metrics in the registry. Registering a metric that already exists
+// returns the existing one, so it is safe to call it for each module instance
+func registerMetrics(registry *metrics.Registry) (*kubernetesMetrics, error) {
+	var (
+		m   kubernetesMetrics
+		err error
+	)
+
+	m.RequestDuration, err = registry.NewMetric("k8s_api_req_duration", metrics.Trend, metrics.Time)
+	if err != nil {
+		return nil, err
+	}
+	m.Requests, err = registry.NewMetric("k8s_api_reqs", metrics.Counter)
+	if err != nil {
+		return nil, err
+	}
+	m.RequestFailed, err = registry.NewMetric("k8s_api_req_failed", metrics.Rate)
+	if err != nil {
+		return nil, err
+	}
+	m.ThrottleWait, err = registry.NewMetric("k8s_api_throttle_wait", metrics.Trend, metrics.Time)
+	if err != nil {
+		return nil, err
+	}
+	m.ClientCacheHits, err = registry.NewMetric("k8s_client_cache_hits", metrics.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// clientScope identifies the VU that sends the requests of a client. As the clients can be shared by
+// VUs, it is passed in the context of the requests for reporting the metrics to the VU
+type clientScope struct {
+	vu modules.VU
+	// cacheHit indicates if the client reused the cached clients for its configuration
+	cacheHit bool
+	// cacheReported is set once the cache hit has been reported
+	cacheReported atomic.Bool
+}
+
+// clientScopeKey is the key of the client scope in the context
+type clientScopeKey struct{}
+
+// withClientScope returns a context with the client scope
+func withClientScope(ctx context.Context, scope *clientScope) context.Context {
+	return context.WithValue(ctx, clientScopeKey{}, scope)
+}
+
+// clientScopeFrom returns the client scope of the context, if any
+func clientScopeFrom(ctx context.Context) *clientScope {
+	scope, _ := ctx.Value(clientScopeKey{}).(*clientScope)
+	return scope
+}
+
+// state returns the state of the VU in the scope. Returns nil outside the VU context
+func (s *clientScope) state() *lib.State {
+	if s == nil || s.vu == nil {
+		return nil
+	}
+	return s.vu.State()
+}
+
+// metricsTransport is a http.RoundTripper that reports the metrics of each request sent to the
+// Kubernetes API server
+type metricsTransport struct {
+	base    http.RoundTripper
+	metrics *kubernetesMetrics
+	kinds   *resourceKinds
+}
+
+// newMetricsTransport returns a function for wrapping the transport of a rest.Config. The kinds of the
+// requested resources are resolved with the given resourceKinds
+func newMetricsTransport(m *kubernetesMetrics, kinds *resourceKinds) func(http.RoundTripper) http.RoundTripper {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &metricsTransport{
+			base:    base,
+			metrics: m,
+			kinds:   kinds,
+		}
+	}
+}
+
+// RoundTrip sends the request and reports its metrics to the VU in the client scope of the request.
+// Metrics are only reported in the VU context
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+
+	scope := clientScopeFrom(req.Context())
+	state := scope.state()
+	if state == nil || t.metrics == nil {
+		return resp, err
+	}
+
+	info := parseRequest(req)
+	tagsAndMeta := state.Tags.GetCurrentValues()
+	tags := tagsAndMeta.Tags.With("verb", info.verb)
+	if kind := t.kinds.kindFor(info.resource); kind != "" {
+		tags = tags.With("kind", kind)
+	}
+	if info.namespace != "" {
+		tags = tags.With("namespace", info.namespace)
+	}
+
+	failed := err != nil
+	if resp != nil {
+		tags = tags.With("status", strconv.Itoa(resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			failed = true
+			if reason := statusReason(resp); reason != "" {
+				tags = tags.With("reason", reason)
+			}
+		}
+	}
+
+	failedValue := 0.0
+	if failed {
+		failedValue = 1
+	}
+
+	now := time.Now()
+	samples := []metrics.Sample{
+		{
+			TimeSeries: metrics.TimeSeries{Metric: t.metrics.RequestDuration, Tags: tags},
+			Time:       now,
+			Value:      metrics.D(duration),
+			Metadata:   tagsAndMeta.Metadata,
+		},
+		{
+			TimeSeries: metrics.TimeSeries{Metric: t.metrics.Requests, Tags: tags},
+			Time:       now,
+			Value:      1,
+			Metadata:   tagsAndMeta.Metadata,
+		},
+		{
+			TimeSeries: metrics.TimeSeries{Metric: t.metrics.RequestFailed, Tags: tags},
+			Time:       now,
+			Value:      failedValue,
+			Metadata:   tagsAndMeta.Metadata,
+		},
+	}
+	metrics.PushIfNotDone(scope.vu.Context(), state.Samples, metrics.ConnectedSamples{
+		Samples: samples,
+		Tags:    tags,
+		Time:    now,
+	})
+
+	// the cache hit is reported on the first request of the client in the VU context, as clients are
+	// usually created in the init context, where metrics cannot be reported
+	if scope.cacheReported.CompareAndSwap(false, true) {
+		cacheHit := 0.0
+		if scope.cacheHit {
+			cacheHit = 1
+		}
+		metrics.PushIfNotDone(scope.vu.Context(), state.Samples, metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: t.metrics.ClientCacheHits, Tags: tagsAndMeta.Tags},
+			Time:       now,
+			Value:      cacheHit,
+			Metadata:   tagsAndMeta.Metadata,
+		})
+	}
+
+	return resp, err
+}
+
+// resourceKinds resolves the kinds of the requested resources. The kinds of the built-in types are known
+// beforehand and the kinds of other resources, such as custom resources, are resolved with the RESTMapper.
+// The RESTMapper is set once created, as its discovery requests are sent with the transport reporting the
+// metrics
+type resourceKinds struct {
+	mapper atomic.Value
+	// builtin maps the resources of the built-in types to their kinds. Built lazily on the first request
+	builtin     map[schema.GroupVersionResource]string
+	builtinOnce sync.Once
+}
+
+// setMapper sets the RESTMapper for resolving the kinds of the resources that are not built-in types
+func (k *resourceKinds) setMapper(mapper meta.RESTMapper) {
+	k.mapper.Store(mapper)
+}
+
+// kindFor returns the kind of the resource. Returns an empty string if the kind is unknown
+func (k *resourceKinds) kindFor(gvr schema.GroupVersionResource) string {
+	if k == nil || gvr.Resource == "" {
+		return ""
+	}
+
+	k.builtinOnce.Do(func() {
+		k.builtin = map[schema.GroupVersionResource]string{}
+		for gvk := range scheme.Scheme.AllKnownTypes() {
+			plural, _ := meta.UnsafeGuessKindToResource(gvk)
+			k.builtin[plural] = gvk.Kind
+		}
+	})
+
+	if kind, found := k.builtin[gvr]; found {
+		return kind
+	}
+
+	mapper, _ := k.mapper.Load().(meta.RESTMapper)
+	if mapper == nil {
+		return ""
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return ""
+	}
+	return gvk.Kind
+}
+
+// statusReason returns the reason of the Status returned by the server in the body of a failed response.
+// The body is restored for the client to read it. Returns an empty string if the body is not a Status
+func statusReason(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	// the rest of the body, if reading it failed, is still read from the original body
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	status := &metav1.Status{}
+	_, _, err := scheme.Codecs.UniversalDeserializer().Decode(body, nil, status)
+	if err != nil {
+		return ""
+	}
+	return string(status.Reason)
+}
+
+// requestInfo describes the request sent to the Kubernetes API server
+type requestInfo struct {
+	verb      string
+	namespace string
+	resource  schema.GroupVersionResource
+}
+
+// parseRequest returns the verb, namespace and resource of a request from its method and path, following
+// the URL conventions of the Kubernetes API:
+//
+//	/api/{version}/[namespaces/{namespace}/]{resource}[/{name}[/{subresource}]]
+//	/apis/{group}/{version}/[namespaces/{namespace}/]{resource}[/{name}[/{subresource}]]
+func parseRequest(req *http.Request) requestInfo {
+	info := requestInfo{}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		info.resource.Version = segments[1]
+		segments = segments[2:]
+	case len(segments) >= 3 && segments[0] == "apis":
+		info.resource.Group = segments[1]
+		info.resource.Version = segments[2]
+		segments = segments[3:]
+	default:
+		// not a resource request (e.g. discovery or version)
+		segments = nil
+	}
+
+	watch := req.URL.Query().Get("watch") == "true"
+	if len(segments) > 0 && segments[0] == "watch" {
+		watch = true
+		segments = segments[1:]
+	}
+
+	// namespaces/{namespace} is a prefix only if it is followed by a resource
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		info.namespace = segments[1]
+		segments = segments[2:]
+	}
+
+	hasName := false
+	if len(segments) > 0 {
+		info.resource.Resource = segments[0]
+		hasName = len(segments) > 1
+	}
+
+	info.verb = requestVerb(req.Method, hasName, watch)
+
+	return info
+}
+
+// requestVerb returns the Kubernetes verb for the HTTP method of a request
+func requestVerb(method string, hasName bool, watch bool) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case watch:
+			return "watch"
+		case hasName:
+			return "get"
+		default:
+			return "list"
+		}
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if hasName {
+			return "delete"
+		}
+		return "deletecollection"
+	default:
+		return strings.ToLower(method)
+	}
+}
@@ -0,0 +1,202 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	localutils "github.com/grafana/xk6-kubernetes/internal/testutils"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/v2/js/modulestest"
+	"go.k6.io/k6/v2/lib"
+	"go.k6.io/k6/v2/metrics"
+)
+
+// roundTripperFunc allows using a function as a http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test      string
+		method    string
+		url       string
+		verb      string
+		namespace string
+		resource  string
+	}{
+		{
+			test:      "get namespaced object",
+			method:    http.MethodGet,
+			url:       "/api/v1/namespaces/testns/pods/busybox",
+			verb:      "get",
+			namespace: "testns",
+			resource:  "pods",
+		},
+		{
+			test:     "list cluster objects",
+			method:   http.MethodGet,
+			url:      "/apis/rbac.authorization.k8s.io/v1/clusterroles",
+			verb:     "list",
+			resource: "clusterroles",
+		},
+		{
+			test:     "get namespace",
+			method:   http.MethodGet,
+			url:      "/api/v1/namespaces/testns",
+			verb:     "get",
+			resource: "namespaces",
+		},
+		{
+			test:      "watch objects",
+			method:    http.MethodGet,
+			url:       "/apis/apps/v1/namespaces/testns/deployments?watch=true",
+			verb:      "watch",
+			namespace: "testns",
+			resource:  "deployments",
+		},
+		{
+			test:      "create in subresource",
+			method:    http.MethodPost,
+			url:       "/api/v1/namespaces/testns/pods/busybox/exec",
+			verb:      "create",
+			namespace: "testns",
+			resource:  "pods",
+		},
+		{
+			test:      "delete collection",
+			method:    http.MethodDelete,
+			url:       "/api/v1/namespaces/testns/pods",
+			verb:      "deletecollection",
+			namespace: "testns",
+			resource:  "pods",
+		},
+		{
+			test:   "discovery",
+			method: http.MethodGet,
+			url:    "/apis",
+			verb:   "list",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			info := parseRequest(req)
+			require.Equal(t, tc.verb, info.verb)
+			require.Equal(t, tc.namespace, info.namespace)
+			require.Equal(t, tc.resource, info.resource.Resource)
+		})
+	}
+}
+
+func TestMetricsTransport(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	m, err := registerMetrics(registry)
+	require.NoError(t, err)
+
+	samples := make(chan metrics.SampleContainer, 10)
+	vu := &modulestest.VU{
+		CtxField: context.Background(),
+		StateField: &lib.State{
+			Samples: samples,
+			Tags:    lib.NewVUStateTags(registry.RootTagSet()),
+		},
+	}
+
+	ctx := withClientScope(context.Background(), &clientScope{vu: vu, cacheHit: true})
+
+	kinds := &resourceKinds{}
+	kinds.setMapper(&localutils.FakeRESTMapper{})
+	status := `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`
+	transport := newMetricsTransport(m, kinds)(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(status))}, nil
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/apis/apps/v1/namespaces/testns/deployments/nginx", nil)
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	require.NoError(t, err)
+	// the body is still readable after decoding the reason
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, status, string(body))
+
+	container := <-samples
+	reported := map[string]float64{}
+	for _, sample := range container.GetSamples() {
+		reported[sample.Metric.Name] = sample.Value
+		require.Equal(t, map[string]string{
+			"verb":      "get",
+			"kind":      "Deployment",
+			"namespace": "testns",
+			"status":    "404",
+			"reason":    "NotFound",
+		}, sample.Tags.Map())
+	}
+	require.Contains(t, reported, "k8s_api_req_duration")
+	require.Equal(t, 1.0, reported["k8s_api_reqs"])
+	require.Equal(t, 1.0, reported["k8s_api_req_failed"])
+
+	// the cache hit is reported only with the first request
+	container = <-samples
+	require.Equal(t, "k8s_client_cache_hits", container.GetSamples()[0].Metric.Name)
+	require.Equal(t, 1.0, container.GetSamples()[0].Value)
+
+	// the reason is taken from the status, not guessed from the status code, and the kinds of resources
+	// that are not built-in types are resolved with the mapper
+	status = `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"AlreadyExists","code":409}`
+	transport = newMetricsTransport(m, kinds)(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusConflict, Body: io.NopCloser(strings.NewReader(status))}, nil
+	}))
+	req = httptest.NewRequest(http.MethodPost, "/apis/apiextensions.k8s.io/v1/customresourcedefinitions", nil)
+	_, err = transport.RoundTrip(req.WithContext(ctx))
+	require.NoError(t, err)
+
+	container = <-samples
+	for _, sample := range container.GetSamples() {
+		require.Equal(t, map[string]string{
+			"verb":   "create",
+			"kind":   "CustomResourceDefinition",
+			"status": "409",
+			"reason": "AlreadyExists",
+		}, sample.Tags.Map())
+	}
+
+	transport = newMetricsTransport(m, kinds)(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/testns/pods", nil)
+	_, err = transport.RoundTrip(req.WithContext(ctx))
+	require.Error(t, err)
+
+	container = <-samples
+	for _, sample := range container.GetSamples() {
+		if sample.Metric.Name == "k8s_api_req_failed" {
+			require.Equal(t, 1.0, sample.Value)
+		}
+		require.Equal(t, map[string]string{
+			"verb":      "list",
+			"kind":      "Pod",
+			"namespace": "testns",
+		}, sample.Tags.Map())
+	}
+	require.Empty(t, samples)
+
+	// requests without a client scope are not reported
+	_, err = transport.RoundTrip(httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/testns/pods", nil))
+	require.Error(t, err)
+	require.Empty(t, samples)
+}
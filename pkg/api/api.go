@@ -22,8 +22,20 @@ import (
 // generic functions that operate on any kind of object
 type Kubernetes interface {
 	resources.UnstructuredOperations
-	// Helpers returns helpers for the given namespace. If none is specified, "default" is used
+	resources.SubresourceOperations
+	resources.WatchOperations
+	AuthorizationOperations
+	// Helpers returns helpers for the given namespace. If none is specified, the default namespace is used
 	Helpers(namespace string) helpers.Helpers
+	// RefreshDiscovery discards the kinds discovered from the cluster, so they are discovered again in
+	// the next request. Returns false if the kinds cannot be discovered again
+	RefreshDiscovery() bool
+	// HasKind returns true if the kind is served by the cluster. The discovery is not refreshed if it is not found
+	HasKind(kind string) (bool, error)
+	// ServerVersion returns the version of the Kubernetes API server
+	ServerVersion() (*ServerVersion, error)
+	// APIResources returns the resources served by the cluster in their preferred version
+	APIResources(options ...APIResourcesOptions) ([]APIResource, error)
 }
 
 // KubernetesConfig defines the configuration for creating a Kubernetes instance
@@ -38,14 +50,21 @@ type KubernetesConfig struct {
 	Client dynamic.Interface
 	// Mapper is a pre-configured RESTMapper. If provided, the rest config is not used
 	Mapper meta.RESTMapper
+	// Namespace is the default namespace for the operations. If not provided, "default" is used
+	Namespace string
+	// Discovery is a pre-configured discovery client. If not provided, the clientset's discovery client
+	// is used or, if there is no clientset, one is created from the rest config
+	Discovery discovery.DiscoveryInterface
 }
 
 // kubernetes holds references to implementation of the Kubernetes interface
 type kubernetes struct {
 	ctx       context.Context
+	namespace string
 	Clientset k8s.Interface
 	*resources.Client
-	Config *rest.Config
+	Config    *rest.Config
+	discovery discovery.DiscoveryInterface
 	*restmapper.DeferredDiscoveryRESTMapper
 }
 
@@ -73,24 +92,49 @@ func NewFromConfig(c KubernetesConfig) (Kubernetes, error) {
 
 	if c.Mapper == nil {
 		discoveryClient, err = discovery.NewDiscoveryClientForConfig(c.Config)
-		mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
 		if err != nil {
 			return nil, err
 		}
-		client.WithMapper(mapper)
+		cachedDiscovery := memory.NewMemCacheClient(discoveryClient)
+		mapper := restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery)
+		// expands the short names of the resources (e.g. "deploy")
+		client.WithMapper(restmapper.NewShortcutExpander(mapper, cachedDiscovery, nil))
+	}
+
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = resources.DefaultNamespace
+	}
+	client.WithNamespace(namespace)
+
+	discoveryInterface := c.Discovery
+	if discoveryInterface == nil {
+		switch {
+		case c.Clientset != nil:
+			discoveryInterface = c.Clientset.Discovery()
+		case discoveryClient != nil:
+			discoveryInterface = discoveryClient
+		default:
+			discoveryInterface, err = discovery.NewDiscoveryClientForConfig(c.Config)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &kubernetes{
 		ctx:       ctx,
+		namespace: namespace,
 		Clientset: c.Clientset,
 		Client:    client,
 		Config:    c.Config,
+		discovery: discoveryInterface,
 	}, nil
 }
 
 func (k *kubernetes) Helpers(namespace string) helpers.Helpers {
 	if namespace == "" {
-		namespace = "default"
+		namespace = k.namespace
 	}
 	return helpers.NewHelper(
 		k.ctx,
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AuthorizationOperations defines functions for reviewing the actions allowed to users in the cluster
+type AuthorizationOperations interface {
+	// CanI reviews if the client is allowed to perform the verb on objects of the kind
+	CanI(verb string, kind string, options ...AccessReviewOptions) (*AccessReview, error)
+	// SubjectAccessReview reviews if a user is allowed to perform the verb on objects of the kind
+	SubjectAccessReview(
+		user string,
+		groups []string,
+		verb string,
+		kind string,
+		options ...AccessReviewOptions,
+	) (*AccessReview, error)
+	// RulesFor returns the actions the client is allowed to perform in the namespace
+	RulesFor(namespace string) (*RulesReview, error)
+}
+
+// AccessReviewOptions defines the object an access review is requested for
+type AccessReviewOptions struct {
+	// Namespace of the object. Defaults to the default namespace for namespaced kinds
+	Namespace string `js:"namespace"`
+	// AllNamespaces reviews the access to the objects of a namespaced kind in all the namespaces
+	AllNamespaces bool `js:"allNamespaces"`
+	// Name of the object. If empty, access to all the objects of the kind is reviewed
+	Name string `js:"name"`
+	// Subresource of the object (e.g. "log" or "scale")
+	Subresource string `js:"subresource"`
+}
+
+// AccessReview describes the result of an access review
+type AccessReview struct {
+	// Allowed indicates if the action is allowed
+	Allowed bool `js:"allowed"`
+	// Denied indicates if the action is explicitly denied. An action can be neither allowed nor denied,
+	// if no authorizer has an opinion on it
+	Denied bool `js:"denied"`
+	// Reason of the decision, if given by the authorizer
+	Reason string `js:"reason"`
+	// EvaluationError describes the errors found evaluating the access, if any
+	EvaluationError string `js:"evaluationError"`
+}
+
+// ResourceRule describes the actions allowed on resources
+type ResourceRule struct {
+	Verbs         []string `js:"verbs"`
+	APIGroups     []string `js:"apiGroups"`
+	Resources     []string `js:"resources"`
+	ResourceNames []string `js:"resourceNames"`
+}
+
+// NonResourceRule describes the actions allowed on non-resource URLs (e.g. "/healthz")
+type NonResourceRule struct {
+	Verbs           []string `js:"verbs"`
+	NonResourceURLs []string `js:"nonResourceURLs"`
+}
+
+// RulesReview describes the actions the user is allowed to perform in a namespace
+type RulesReview struct {
+	ResourceRules    []ResourceRule    `js:"resourceRules"`
+	NonResourceRules []NonResourceRule `js:"nonResourceRules"`
+	// Incomplete indicates the list of rules is not complete, for example if the authorizer does not support
+	// listing rules
+	Incomplete bool `js:"incomplete"`
+	// EvaluationError describes the errors found evaluating the rules, if any
+	EvaluationError string `js:"evaluationError"`
+}
+
+// CanI reviews if the client is allowed to perform the verb on objects of the kind
+func (k *kubernetes) CanI(verb string, kind string, options ...AccessReviewOptions) (*AccessReview, error) {
+	attributes, err := k.resourceAttributes(verb, kind, options)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := k.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(
+		k.ctx,
+		&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: attributes,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAccessReview(review.Status), nil
+}
+
+// SubjectAccessReview reviews if the user, member of the groups, is allowed to perform the verb on objects
+// of the kind
+func (k *kubernetes) SubjectAccessReview(
+	user string,
+	groups []string,
+	verb string,
+	kind string,
+	options ...AccessReviewOptions,
+) (*AccessReview, error) {
+	attributes, err := k.resourceAttributes(verb, kind, options)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := k.Clientset.AuthorizationV1().SubjectAccessReviews().Create(
+		k.ctx,
+		&authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: attributes,
+				User:               user,
+				Groups:             groups,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAccessReview(review.Status), nil
+}
+
+// RulesFor returns the actions the client is allowed to perform in the namespace. If no namespace is
+// given, the default namespace is used
+func (k *kubernetes) RulesFor(namespace string) (*RulesReview, error) {
+	if namespace == "" {
+		namespace = k.namespace
+	}
+
+	review, err := k.Clientset.AuthorizationV1().SelfSubjectRulesReviews().Create(
+		k.ctx,
+		&authorizationv1.SelfSubjectRulesReview{
+			Spec: authorizationv1.SelfSubjectRulesReviewSpec{
+				Namespace: namespace,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &RulesReview{
+		ResourceRules:    []ResourceRule{},
+		NonResourceRules: []NonResourceRule{},
+		Incomplete:       review.Status.Incomplete,
+		EvaluationError:  review.Status.EvaluationError,
+	}
+	for _, rule := range review.Status.ResourceRules {
+		rules.ResourceRules = append(rules.ResourceRules, ResourceRule{
+			Verbs:         rule.Verbs,
+			APIGroups:     rule.APIGroups,
+			Resources:     rule.Resources,
+			ResourceNames: rule.ResourceNames,
+		})
+	}
+	for _, rule := range review.Status.NonResourceRules {
+		rules.NonResourceRules = append(rules.NonResourceRules, NonResourceRule{
+			Verbs:           rule.Verbs,
+			NonResourceURLs: rule.NonResourceURLs,
+		})
+	}
+
+	return rules, nil
+}
+
+// resourceAttributes returns the attributes of the access review for the verb on objects of the kind
+func (k *kubernetes) resourceAttributes(
+	verb string,
+	kind string,
+	options []AccessReviewOptions,
+) (*authorizationv1.ResourceAttributes, error) {
+	mapping, err := k.MappingFor(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := AccessReviewOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.AllNamespaces && opts.Namespace != "" {
+		return nil, errors.New("namespace cannot be given when reviewing access in all namespaces")
+	}
+
+	attributes := &authorizationv1.ResourceAttributes{
+		Verb:        verb,
+		Group:       mapping.Resource.Group,
+		Version:     mapping.Resource.Version,
+		Resource:    mapping.Resource.Resource,
+		Subresource: opts.Subresource,
+		Name:        opts.Name,
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && !opts.AllNamespaces {
+		attributes.Namespace = opts.Namespace
+		if attributes.Namespace == "" {
+			attributes.Namespace = k.namespace
+		}
+	}
+
+	return attributes, nil
+}
+
+// toAccessReview returns the result of an access review
+func toAccessReview(status authorizationv1.SubjectAccessReviewStatus) *AccessReview {
+	return &AccessReview{
+		Allowed:         status.Allowed,
+		Denied:          status.Denied,
+		Reason:          status.Reason,
+		EvaluationError: status.EvaluationError,
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stest "k8s.io/client-go/testing"
+)
+
+// allowGetPods is a reactor for access reviews that only allows getting pods in the testns namespace and
+// listing pods in all namespaces
+func allowGetPods(action k8stest.Action) (bool, runtime.Object, error) {
+	create, ok := action.(k8stest.CreateAction)
+	if !ok {
+		return false, nil, nil
+	}
+
+	var (
+		attributes *authorizationv1.ResourceAttributes
+		user       string
+	)
+	switch review := create.GetObject().(type) {
+	case *authorizationv1.SelfSubjectAccessReview:
+		attributes = review.Spec.ResourceAttributes
+		user = "self"
+	case *authorizationv1.SubjectAccessReview:
+		attributes = review.Spec.ResourceAttributes
+		user = review.Spec.User
+	default:
+		return false, nil, nil
+	}
+
+	status := authorizationv1.SubjectAccessReviewStatus{Denied: true, Reason: "denied by test"}
+	allowed := attributes.Resource == "pods" &&
+		((attributes.Verb == "get" && attributes.Namespace == "testns") ||
+			(attributes.Verb == "list" && attributes.Namespace == ""))
+	if user != "mallory" && allowed {
+		status = authorizationv1.SubjectAccessReviewStatus{Allowed: true, Reason: "allowed by test"}
+	}
+
+	if self, isSelf := create.GetObject().(*authorizationv1.SelfSubjectAccessReview); isSelf {
+		self.Status = status
+		return true, self, nil
+	}
+	review, _ := create.GetObject().(*authorizationv1.SubjectAccessReview)
+	review.Status = status
+	return true, review, nil
+}
+
+func TestCanI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test        string
+		verb        string
+		kind        string
+		options     []AccessReviewOptions
+		expectError bool
+		allowed     bool
+	}{
+		{
+			test:    "allowed in default namespace",
+			verb:    "get",
+			kind:    "Pod",
+			allowed: true,
+		},
+		{
+			test:    "allowed in namespace",
+			verb:    "get",
+			kind:    "pods",
+			options: []AccessReviewOptions{{Namespace: "testns", Name: "busybox"}},
+			allowed: true,
+		},
+		{
+			test:    "denied in other namespace",
+			verb:    "get",
+			kind:    "Pod",
+			options: []AccessReviewOptions{{Namespace: "other"}},
+		},
+		{
+			test:    "allowed in all namespaces",
+			verb:    "list",
+			kind:    "Pod",
+			options: []AccessReviewOptions{{AllNamespaces: true}},
+			allowed: true,
+		},
+		{
+			test: "denied in default namespace",
+			verb: "list",
+			kind: "Pod",
+		},
+		{
+			test:        "namespace and all namespaces",
+			verb:        "list",
+			kind:        "Pod",
+			options:     []AccessReviewOptions{{Namespace: "testns", AllNamespaces: true}},
+			expectError: true,
+		},
+		{
+			test: "denied verb",
+			verb: "delete",
+			kind: "Pod",
+		},
+		{
+			test:        "unknown kind",
+			verb:        "get",
+			kind:        "Gateway",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			k, clientset := newFakeKubernetes(t)
+			clientset.PrependReactor("create", "selfsubjectaccessreviews", allowGetPods)
+
+			review, err := k.CanI(tc.verb, tc.kind, tc.options...)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if review.Allowed != tc.allowed || review.Denied == tc.allowed {
+				t.Errorf("expected allowed %t but got %+v", tc.allowed, review)
+			}
+		})
+	}
+}
+
+func TestSubjectAccessReview(t *testing.T) {
+	t.Parallel()
+
+	k, clientset := newFakeKubernetes(t)
+	clientset.PrependReactor("create", "subjectaccessreviews", allowGetPods)
+
+	review, err := k.SubjectAccessReview("alice", []string{"tenant-a"}, "get", "Pod")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !review.Allowed || review.Reason != "allowed by test" {
+		t.Errorf("expected access allowed but got %+v", review)
+	}
+
+	review, err = k.SubjectAccessReview("mallory", nil, "get", "Pod")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if review.Allowed || !review.Denied {
+		t.Errorf("expected access denied but got %+v", review)
+	}
+}
+
+func TestRulesFor(t *testing.T) {
+	t.Parallel()
+
+	k, clientset := newFakeKubernetes(t)
+	clientset.PrependReactor(
+		"create",
+		"selfsubjectrulesreviews",
+		func(action k8stest.Action) (bool, runtime.Object, error) {
+			create, _ := action.(k8stest.CreateAction)
+			review, _ := create.GetObject().(*authorizationv1.SelfSubjectRulesReview)
+			if review.Spec.Namespace == "testns" {
+				review.Status.ResourceRules = []authorizationv1.ResourceRule{
+					{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+				}
+			}
+			review.Status.NonResourceRules = []authorizationv1.NonResourceRule{
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}},
+			}
+			return true, review, nil
+		},
+	)
+
+	rules, err := k.RulesFor("")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(rules.ResourceRules) != 1 || rules.ResourceRules[0].Resources[0] != "pods" {
+		t.Errorf("expected rules for pods in the default namespace but got %+v", rules.ResourceRules)
+	}
+	if len(rules.NonResourceRules) != 1 {
+		t.Errorf("expected non-resource rules but got %+v", rules.NonResourceRules)
+	}
+
+	rules, err = k.RulesFor("other")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(rules.ResourceRules) != 0 {
+		t.Errorf("expected no rules in other namespace but got %+v", rules.ResourceRules)
+	}
+}
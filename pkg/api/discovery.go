@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+// coreGroup is the name used for selecting the core (legacy) API group, which has no name
+const coreGroup = "core"
+
+// ServerVersion describes the version of the Kubernetes API server
+type ServerVersion struct {
+	// Major version (e.g. 1)
+	Major int `js:"major"`
+	// Minor version (e.g. 29)
+	Minor int `js:"minor"`
+	// GitVersion is the full version (e.g. "v1.29.2")
+	GitVersion string `js:"gitVersion"`
+	// Platform the server runs on (e.g. "linux/amd64")
+	Platform string `js:"platform"`
+}
+
+// APIResourcesOptions defines the options for filtering the resources returned by APIResources
+type APIResourcesOptions struct {
+	// Group restricts the resources to an API group. Use "core" for the core group
+	Group string `js:"group"`
+}
+
+// APIResource describes a resource served by the cluster
+type APIResource struct {
+	// Name is the plural name of the resource (e.g. "deployments")
+	Name string `js:"name"`
+	// ShortNames are the abbreviations of the resource (e.g. "deploy")
+	ShortNames []string `js:"shortNames"`
+	// Verbs supported by the resource (e.g. "get", "list", "watch")
+	Verbs []string `js:"verbs"`
+	// Namespaced indicates if the objects of the resource belong to a namespace
+	Namespaced bool `js:"namespaced"`
+	// Kind of the objects of the resource (e.g. "Deployment")
+	Kind string `js:"kind"`
+	// Group of the resource. Empty for the core group
+	Group string `js:"group"`
+	// Version of the resource preferred by the server
+	Version string `js:"version"`
+}
+
+// ServerVersion returns the version of the Kubernetes API server
+func (k *kubernetes) ServerVersion() (*ServerVersion, error) {
+	info, err := k.discovery.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	// the major and minor fields may have suffixes added by some distributions (e.g. "29+"), so the
+	// numbers are taken from the git version
+	parsed, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server version %q: %w", info.GitVersion, err)
+	}
+
+	return &ServerVersion{
+		Major:      int(parsed.Major()),
+		Minor:      int(parsed.Minor()),
+		GitVersion: info.GitVersion,
+		Platform:   info.Platform,
+	}, nil
+}
+
+// APIResources returns the resources served by the cluster in their preferred version, sorted by group
+// and name. Subresources are not included. Groups that cannot be discovered (e.g. due to an unavailable
+// aggregated API server) are ignored
+func (k *kubernetes) APIResources(options ...APIResourcesOptions) ([]APIResource, error) {
+	group := ""
+	filter := false
+	if len(options) > 0 && options[0].Group != "" {
+		filter = true
+		group = options[0].Group
+		if group == coreGroup {
+			group = ""
+		}
+	}
+
+	lists, err := discovery.ServerPreferredResources(k.discovery)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	resources := []APIResource{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		if filter && gv.Group != group {
+			continue
+		}
+
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			resources = append(resources, APIResource{
+				Name:       resource.Name,
+				ShortNames: resource.ShortNames,
+				Verbs:      resource.Verbs,
+				Namespaced: resource.Namespaced,
+				Kind:       resource.Kind,
+				Group:      gv.Group,
+				Version:    gv.Version,
+			})
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Group != resources[j].Group {
+			return resources[i].Group < resources[j].Group
+		}
+		return resources[i].Name < resources[j].Name
+	})
+
+	return resources, nil
+}
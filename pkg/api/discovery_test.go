@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/xk6-kubernetes/internal/testutils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeKubernetes(t *testing.T) (Kubernetes, *fake.Clientset) {
+	t.Helper()
+
+	clientset, ok := testutils.NewFakeClientset().(*fake.Clientset)
+	if !ok {
+		t.Fatalf("unexpected fake clientset")
+	}
+
+	dynamic, err := testutils.NewFakeDynamic()
+	if err != nil {
+		t.Fatalf("unexpected error creating fake client %v", err)
+	}
+
+	k, err := NewFromConfig(KubernetesConfig{
+		Context:   context.TODO(),
+		Clientset: clientset,
+		Client:    dynamic,
+		Mapper:    &testutils.FakeRESTMapper{},
+		Namespace: "testns",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating client %v", err)
+	}
+
+	return k, clientset
+}
+
+// fakeDiscovery returns the fake discovery client of the fake clientset
+func fakeDiscovery(t *testing.T, clientset *fake.Clientset) *fakediscovery.FakeDiscovery {
+	t.Helper()
+
+	discovery, ok := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	if !ok {
+		t.Fatalf("unexpected discovery client %T", clientset.Discovery())
+	}
+	return discovery
+}
+
+func TestServerVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test        string
+		info        version.Info
+		expectError bool
+		major       int
+		minor       int
+	}{
+		{
+			test:  "release version",
+			info:  version.Info{Major: "1", Minor: "29", GitVersion: "v1.29.2", Platform: "linux/amd64"},
+			major: 1,
+			minor: 29,
+		},
+		{
+			test:  "distribution version",
+			info:  version.Info{Major: "1", Minor: "28+", GitVersion: "v1.28.5-eks-5e0fdde"},
+			major: 1,
+			minor: 28,
+		},
+		{
+			test:        "invalid version",
+			info:        version.Info{GitVersion: "unknown"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			k, clientset := newFakeKubernetes(t)
+			discovery := fakeDiscovery(t, clientset)
+			discovery.FakedServerVersion = &tc.info
+
+			serverVersion, err := k.ServerVersion()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if serverVersion.Major != tc.major || serverVersion.Minor != tc.minor {
+				t.Errorf("expected version %d.%d but got %d.%d",
+					tc.major, tc.minor, serverVersion.Major, serverVersion.Minor)
+			}
+			if serverVersion.GitVersion != tc.info.GitVersion {
+				t.Errorf("expected git version %s but got %s", tc.info.GitVersion, serverVersion.GitVersion)
+			}
+		})
+	}
+}
+
+func TestAPIResources(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test     string
+		options  []APIResourcesOptions
+		expected []string
+	}{
+		{
+			test:     "all groups",
+			expected: []string{"pods", "services", "deployments"},
+		},
+		{
+			test:     "core group",
+			options:  []APIResourcesOptions{{Group: "core"}},
+			expected: []string{"pods", "services"},
+		},
+		{
+			test:     "named group",
+			options:  []APIResourcesOptions{{Group: "apps"}},
+			expected: []string{"deployments"},
+		},
+		{
+			test:     "unknown group",
+			options:  []APIResourcesOptions{{Group: "gateway.networking.k8s.io"}},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			k, clientset := newFakeKubernetes(t)
+			discovery := fakeDiscovery(t, clientset)
+			discovery.Resources = []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{
+						{Name: "services", Kind: "Service", Namespaced: true, ShortNames: []string{"svc"}},
+						{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"get", "list"}},
+						{Name: "pods/log", Kind: "Pod", Namespaced: true},
+					},
+				},
+				{
+					GroupVersion: "apps/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "deployments", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}},
+					},
+				},
+			}
+
+			resources, err := k.APIResources(tc.options...)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			names := []string{}
+			for _, resource := range resources {
+				names = append(names, resource.Name)
+			}
+			if len(names) != len(tc.expected) {
+				t.Errorf("expected resources %v but got %v", tc.expected, names)
+				return
+			}
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Errorf("expected resources %v but got %v", tc.expected, names)
+					return
+				}
+			}
+		})
+	}
+}
+
+// resetCountingMapper is a RESTMapper that counts the times it is reset
+type resetCountingMapper struct {
+	testutils.FakeRESTMapper
+	resets int
+}
+
+func (m *resetCountingMapper) Reset() {
+	m.resets++
+}
+
+func TestHasKind(t *testing.T) {
+	t.Parallel()
+
+	dynamic, err := testutils.NewFakeDynamic()
+	if err != nil {
+		t.Fatalf("unexpected error creating fake client %v", err)
+	}
+	mapper := &resetCountingMapper{}
+	k, err := NewFromConfig(KubernetesConfig{
+		Context:   context.TODO(),
+		Clientset: testutils.NewFakeClientset(),
+		Client:    dynamic,
+		Mapper:    mapper,
+		Namespace: "testns",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating client %v", err)
+	}
+
+	found, err := k.HasKind("Deployment.apps")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !found {
+		t.Errorf("expected Deployment to be found")
+	}
+
+	found, err = k.HasKind("Gateway.gateway.networking.k8s.io")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if found {
+		t.Errorf("expected Gateway not to be found")
+	}
+
+	// kinds not found do not discard the discovery
+	if mapper.resets != 0 {
+		t.Errorf("expected discovery not to be refreshed but was refreshed %d times", mapper.resets)
+	}
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// crdKind is the kind of the CustomResourceDefinitions
+const crdKind = "CustomResourceDefinition.apiextensions.k8s.io"
+
+// CRDHelper defines helper functions for manipulating CustomResourceDefinitions
+type CRDHelper interface {
+	// WaitCRDEstablished waits for the CustomResourceDefinition to be established for up to the given timeout
+	// (in seconds) and returns a boolean indicating if it was established. Once established, the kinds known
+	// by the client are discovered again, so the custom resources can be used. If the names of the
+	// CustomResourceDefinition are not accepted an error is returned.
+	WaitCRDEstablished(name string, timeout int64) (bool, error)
+}
+
+// isEstablished returns if the CustomResourceDefinition is established or not. Returns an error if its
+// names are not accepted
+func isEstablished(crd map[string]interface{}) (bool, error) {
+	conditions, _, err := unstructured.NestedSlice(crd, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range conditions {
+		condition, isMap := c.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		if condition["type"] == "NamesAccepted" && condition["status"] == "False" {
+			return false, fmt.Errorf("names not accepted with reason: %v", condition["reason"])
+		}
+		if condition["type"] == "Established" && condition["status"] == "True" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (h *helpers) WaitCRDEstablished(name string, timeout int64) (bool, error) {
+	established, err := utils.Retry(time.Duration(timeout)*time.Second, time.Second, func() (bool, error) {
+		crd, err := h.client.Get(crdKind, name, "")
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to access custom resource definition: %w", err)
+		}
+
+		return isEstablished(crd)
+	})
+	if established {
+		h.client.RefreshDiscovery()
+	}
+
+	return established, err
+}
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/internal/testutils"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+const (
+	crdName = "tests.example.com"
+)
+
+func newCRD(name string, conditions ...map[string]interface{}) map[string]interface{} {
+	status := []interface{}{}
+	for _, condition := range conditions {
+		status = append(status, condition)
+	}
+	return map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+		"status": map[string]interface{}{
+			"conditions": status,
+		},
+	}
+}
+
+func TestWaitCRDEstablished(t *testing.T) {
+	t.Parallel()
+	type TestCase struct {
+		test           string
+		condition      map[string]interface{}
+		delay          time.Duration
+		expectError    bool
+		expectedResult bool
+		timeout        int64
+	}
+
+	testCases := []TestCase{
+		{
+			test:           "crd established before timeout",
+			condition:      map[string]interface{}{"type": "Established", "status": "True"},
+			delay:          1 * time.Second,
+			expectError:    false,
+			expectedResult: true,
+			timeout:        5,
+		},
+		{
+			test:           "timeout waiting for crd to be established",
+			condition:      map[string]interface{}{"type": "Established", "status": "True"},
+			delay:          10 * time.Second,
+			expectError:    false,
+			expectedResult: false,
+			timeout:        3,
+		},
+		{
+			test:           "crd names not accepted",
+			condition:      map[string]interface{}{"type": "NamesAccepted", "status": "False", "reason": "KindConflict"},
+			delay:          1 * time.Second,
+			expectError:    true,
+			expectedResult: false,
+			timeout:        5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, _ := testutils.NewFakeDynamic()
+			client := resources.NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+			clientset := testutils.NewFakeClientset()
+			h := NewHelper(context.TODO(), clientset, client, nil, "default")
+
+			_, err := client.Create(newCRD(crdName))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			go func(tc TestCase) {
+				time.Sleep(tc.delay)
+				_, e := client.Update(newCRD(crdName, tc.condition))
+				if e != nil {
+					t.Errorf("unexpected error: %v", e)
+					return
+				}
+			}(tc)
+
+			result, err := h.WaitCRDEstablished(crdName, tc.timeout)
+
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if tc.expectError && err == nil {
+				t.Error("expected an error but none returned")
+				return
+			}
+			if result != tc.expectedResult {
+				t.Errorf("expected result %t but %t returned", tc.expectedResult, result)
+				return
+			}
+		})
+	}
+}
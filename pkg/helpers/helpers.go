@@ -12,9 +12,12 @@ import (
 
 // Helpers offers Helper functions grouped by the objects they handle
 type Helpers interface {
+	CRDHelper
 	JobHelper
 	PodHelper
+	RolloutHelper
 	ServiceHelper
+	WaitHelper
 }
 
 // helpers struct holds the data required by the helpers
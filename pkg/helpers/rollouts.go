@@ -0,0 +1,424 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+	"github.com/grafana/xk6-kubernetes/pkg/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// progressDeadlineExceeded is the reason of the Progressing condition of a Deployment whose rollout
+	// did not progress in the deadline given in its spec
+	progressDeadlineExceeded = "ProgressDeadlineExceeded"
+	// restartedAtAnnotation is the pod template annotation changed to restart a workload, as kubectl does
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// revisionAnnotation holds the revision of the Deployment a ReplicaSet was created for
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// changeCauseAnnotation holds the cause of the change that created a revision
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// RolloutHelper defines helper functions for the rollouts of workloads: Deployments, StatefulSets and DaemonSets
+type RolloutHelper interface {
+	// WaitRolloutComplete waits for the rollout of the workload to complete for up to the given timeout
+	// (in seconds) and returns a boolean indicating if it was completed, with the same criteria used by
+	// kubectl rollout status. If a Deployment exceeds its progress deadline, or the workload does not
+	// use the RollingUpdate strategy, an error is returned.
+	WaitRolloutComplete(kind string, name string, timeout int64) (bool, error)
+	// RolloutRestart restarts the pods of the workload by triggering a new rollout and returns the workload.
+	// Paused Deployments cannot be restarted.
+	RolloutRestart(kind string, name string) (map[string]interface{}, error)
+	// RolloutPause pauses the rollouts of the Deployment and returns it
+	RolloutPause(kind string, name string) (map[string]interface{}, error)
+	// RolloutResume resumes the rollouts of a paused Deployment and returns it
+	RolloutResume(kind string, name string) (map[string]interface{}, error)
+	// RolloutUndo rolls back the workload to the pod template of the given revision, or to the previous
+	// revision if 0 is given, and returns the workload. Paused Deployments cannot be rolled back.
+	RolloutUndo(kind string, name string, revision int64) (map[string]interface{}, error)
+	// RolloutHistory returns the revisions of the workload sorted from the oldest to the newest. The revisions
+	// of Deployments are taken from their ReplicaSets and the revisions of StatefulSets and DaemonSets from
+	// their ControllerRevisions.
+	RolloutHistory(kind string, name string) ([]RolloutRevision, error)
+}
+
+// RolloutRevision describes a revision in the rollout history of a workload
+type RolloutRevision struct {
+	// Revision number
+	Revision int64 `js:"revision"`
+	// Name of the ReplicaSet or ControllerRevision holding the revision
+	Name string `js:"name"`
+	// ChangeCause is the cause of the change recorded in the kubernetes.io/change-cause annotation, if any
+	ChangeCause string `js:"changeCause"`
+	// Template is the pod template of the revision
+	Template map[string]interface{} `js:"template"`
+}
+
+// rolloutKind returns the kind of the workload, which must be a Deployment, StatefulSet or DaemonSet
+func (h *helpers) rolloutKind(kind string) (string, error) {
+	mapping, err := h.client.MappingFor(kind)
+	if err != nil {
+		return "", err
+	}
+
+	gk := mapping.GroupVersionKind.GroupKind()
+	switch gk {
+	case schema.GroupKind{Group: appsv1.GroupName, Kind: "Deployment"},
+		schema.GroupKind{Group: appsv1.GroupName, Kind: "StatefulSet"},
+		schema.GroupKind{Group: appsv1.GroupName, Kind: "DaemonSet"}:
+		return gk.Kind, nil
+	default:
+		return "", fmt.Errorf("rollouts are not supported for %s", gk)
+	}
+}
+
+// rolloutMatcher returns a function that checks if the rollout of a workload of the given kind is complete
+func (h *helpers) rolloutMatcher(kind string) (objectMatcher, error) {
+	workload, err := h.rolloutKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	switch workload {
+	case "Deployment":
+		return func(obj map[string]interface{}) (bool, error) {
+			deployment := &appsv1.Deployment{}
+			if err := utils.GenericToRuntime(obj, deployment); err != nil {
+				return false, err
+			}
+			return deploymentRolloutComplete(deployment)
+		}, nil
+	case "StatefulSet":
+		return func(obj map[string]interface{}) (bool, error) {
+			statefulSet := &appsv1.StatefulSet{}
+			if err := utils.GenericToRuntime(obj, statefulSet); err != nil {
+				return false, err
+			}
+			return statefulSetRolloutComplete(statefulSet)
+		}, nil
+	default:
+		return func(obj map[string]interface{}) (bool, error) {
+			daemonSet := &appsv1.DaemonSet{}
+			if err := utils.GenericToRuntime(obj, daemonSet); err != nil {
+				return false, err
+			}
+			return daemonSetRolloutComplete(daemonSet)
+		}, nil
+	}
+}
+
+// deploymentRolloutComplete returns true if all the replicas of the Deployment are updated and available and
+// the old replicas are terminated. Returns an error if the Deployment exceeded its progress deadline
+func deploymentRolloutComplete(deployment *appsv1.Deployment) (bool, error) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceeded {
+			return false, fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)
+		}
+	}
+
+	status := deployment.Status
+	if deployment.Spec.Replicas != nil && status.UpdatedReplicas < *deployment.Spec.Replicas {
+		return false, nil
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return false, nil
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return false, nil
+	}
+	return true, nil
+}
+
+// statefulSetRolloutComplete returns true if all the replicas of the StatefulSet are ready and updated. For
+// partitioned updates, only the replicas with an ordinal greater or equal than the partition must be updated
+func statefulSetRolloutComplete(statefulSet *appsv1.StatefulSet) (bool, error) {
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return false, fmt.Errorf("rollout status is only available for %s strategy type",
+			appsv1.RollingUpdateStatefulSetStrategyType)
+	}
+
+	status := statefulSet.Status
+	if status.ObservedGeneration == 0 || statefulSet.Generation > status.ObservedGeneration {
+		return false, nil
+	}
+	if statefulSet.Spec.Replicas != nil && status.ReadyReplicas < *statefulSet.Spec.Replicas {
+		return false, nil
+	}
+
+	rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate != nil && rollingUpdate.Partition != nil && statefulSet.Spec.Replicas != nil {
+		return status.UpdatedReplicas >= *statefulSet.Spec.Replicas-*rollingUpdate.Partition, nil
+	}
+
+	return status.UpdateRevision == status.CurrentRevision, nil
+}
+
+// daemonSetRolloutComplete returns true if the DaemonSet's pods are updated and available in all the nodes
+// they are scheduled to
+func daemonSetRolloutComplete(daemonSet *appsv1.DaemonSet) (bool, error) {
+	if daemonSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return false, fmt.Errorf("rollout status is only available for %s strategy type",
+			appsv1.RollingUpdateDaemonSetStrategyType)
+	}
+
+	status := daemonSet.Status
+	if daemonSet.Generation > status.ObservedGeneration {
+		return false, nil
+	}
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+		return false, nil
+	}
+	if status.NumberAvailable < status.DesiredNumberScheduled || status.NumberUnavailable > 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (h *helpers) WaitRolloutComplete(kind string, name string, timeout int64) (bool, error) {
+	match, err := h.rolloutMatcher(kind)
+	if err != nil {
+		return false, err
+	}
+
+	obj, _, err := h.watchUntil(kind, name, match, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return false, err
+	}
+	return obj != nil, nil
+}
+
+// checkNotPaused returns an error if the workload is a paused Deployment
+func checkNotPaused(obj map[string]interface{}, action string) error {
+	paused, _, err := unstructured.NestedBool(obj, "spec", "paused")
+	if err != nil {
+		return err
+	}
+	if paused {
+		return fmt.Errorf("cannot %s paused deployment %q, resume it first", action,
+			(&unstructured.Unstructured{Object: obj}).GetName())
+	}
+	return nil
+}
+
+func (h *helpers) RolloutRestart(kind string, name string) (map[string]interface{}, error) {
+	if _, err := h.rolloutKind(kind); err != nil {
+		return nil, err
+	}
+
+	obj, err := h.client.Get(kind, name, h.namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNotPaused(obj, "restart"); err != nil {
+		return nil, err
+	}
+
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	return h.client.Patch(kind, name, h.namespace, patch)
+}
+
+// setPaused pauses or resumes the rollouts of a Deployment
+func (h *helpers) setPaused(kind string, name string, paused bool) (map[string]interface{}, error) {
+	workload, err := h.rolloutKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if workload != "Deployment" {
+		return nil, fmt.Errorf("pausing rollouts is not supported for %s", workload)
+	}
+
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused": paused,
+		},
+	}
+	return h.client.Patch(kind, name, h.namespace, patch)
+}
+
+func (h *helpers) RolloutPause(kind string, name string) (map[string]interface{}, error) {
+	return h.setPaused(kind, name, true)
+}
+
+func (h *helpers) RolloutResume(kind string, name string) (map[string]interface{}, error) {
+	return h.setPaused(kind, name, false)
+}
+
+func (h *helpers) RolloutUndo(kind string, name string, revision int64) (map[string]interface{}, error) {
+	workload, err := h.rolloutKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := h.client.Get(kind, name, h.namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNotPaused(obj, "roll back"); err != nil {
+		return nil, err
+	}
+
+	history, err := h.rolloutHistory(workload, obj)
+	if err != nil {
+		return nil, err
+	}
+	target, err := rollbackRevision(history, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// the template is replaced, as merging it would keep the labels and annotations added after the revision
+	patch := []map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": target.Template},
+	}
+	return h.client.Patch(kind, name, h.namespace, patch, resources.PatchOptions{Type: "json"})
+}
+
+// rollbackRevision returns the revision to roll back to from a history sorted by revision. If the revision
+// is 0, the revision previous to the current one is returned
+func rollbackRevision(history []RolloutRevision, revision int64) (RolloutRevision, error) {
+	if revision == 0 {
+		if len(history) < 2 {
+			return RolloutRevision{}, errors.New("no previous revision to roll back to")
+		}
+		return history[len(history)-2], nil
+	}
+
+	for _, r := range history {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return RolloutRevision{}, fmt.Errorf("revision %d not found in history", revision)
+}
+
+func (h *helpers) RolloutHistory(kind string, name string) ([]RolloutRevision, error) {
+	workload, err := h.rolloutKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := h.client.Get(kind, name, h.namespace)
+	if err != nil {
+		return nil, err
+	}
+	return h.rolloutHistory(workload, obj)
+}
+
+// rolloutHistory returns the revisions of the workload, sorted by revision. The revisions are the
+// ReplicaSets (for Deployments) or ControllerRevisions matching the workload's selector and controlled by it
+func (h *helpers) rolloutHistory(workload string, obj map[string]interface{}) ([]RolloutRevision, error) {
+	owner := &unstructured.Unstructured{Object: obj}
+
+	listOptions := resources.ListOptions{}
+	if spec, found, _ := unstructured.NestedMap(obj, "spec", "selector"); found {
+		labelSelector := &metav1.LabelSelector{}
+		if err := utils.GenericToRuntime(spec, labelSelector); err != nil {
+			return nil, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		listOptions.LabelSelector = selector.String()
+	}
+
+	revisionKind, toRevision := "ControllerRevision", revisionFromControllerRevision
+	if workload == "Deployment" {
+		revisionKind, toRevision = "ReplicaSet", revisionFromReplicaSet
+	}
+
+	items, err := h.client.List(revisionKind, h.namespace, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []RolloutRevision{}
+	for _, item := range items {
+		controller := metav1.GetControllerOf(&unstructured.Unstructured{Object: item})
+		if controller == nil || controller.UID != owner.GetUID() {
+			continue
+		}
+		revision, err := toRevision(item)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, revision)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Revision < history[j].Revision
+	})
+	return history, nil
+}
+
+// revisionFromReplicaSet returns the revision of a Deployment held by a ReplicaSet. The pod-template-hash
+// label added by the Deployment controller is removed from the template
+func revisionFromReplicaSet(obj map[string]interface{}) (RolloutRevision, error) {
+	rs := &unstructured.Unstructured{Object: obj}
+	annotations := rs.GetAnnotations()
+
+	revision, err := strconv.ParseInt(annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return RolloutRevision{}, fmt.Errorf("invalid revision in ReplicaSet %q: %w", rs.GetName(), err)
+	}
+
+	template, _, err := unstructured.NestedMap(obj, "spec", "template")
+	if err != nil {
+		return RolloutRevision{}, err
+	}
+	unstructured.RemoveNestedField(template, "metadata", "labels", appsv1.DefaultDeploymentUniqueLabelKey)
+
+	return RolloutRevision{
+		Revision:    revision,
+		Name:        rs.GetName(),
+		ChangeCause: annotations[changeCauseAnnotation],
+		Template:    template,
+	}, nil
+}
+
+// revisionFromControllerRevision returns the revision of a StatefulSet or DaemonSet held by a
+// ControllerRevision, whose data is a patch that replaces the pod template
+func revisionFromControllerRevision(obj map[string]interface{}) (RolloutRevision, error) {
+	cr := &unstructured.Unstructured{Object: obj}
+
+	revision, _, err := unstructured.NestedInt64(obj, "revision")
+	if err != nil {
+		return RolloutRevision{}, err
+	}
+
+	template, _, err := unstructured.NestedMap(obj, "data", "spec", "template")
+	if err != nil {
+		return RolloutRevision{}, err
+	}
+	delete(template, "$patch")
+
+	return RolloutRevision{
+		Revision:    revision,
+		Name:        cr.GetName(),
+		ChangeCause: cr.GetAnnotations()[changeCauseAnnotation],
+		Template:    template,
+	}, nil
+}
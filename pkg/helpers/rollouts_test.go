@@ -0,0 +1,620 @@
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/internal/testutils"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+	"github.com/grafana/xk6-kubernetes/pkg/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func newDeployment(replicas int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "nginx",
+			Namespace:  "default",
+			Generation: 2,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+		},
+		Status: status,
+	}
+}
+
+func TestDeploymentRolloutComplete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test        string
+		deployment  *appsv1.Deployment
+		expectError bool
+		complete    bool
+	}{
+		{
+			test: "spec update not observed",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3,
+			}),
+		},
+		{
+			test: "replicas not updated",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 3,
+			}),
+		},
+		{
+			test: "old replicas pending termination",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3,
+			}),
+		},
+		{
+			test: "updated replicas not available",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2,
+			}),
+		},
+		{
+			test: "complete",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3,
+			}),
+			complete: true,
+		},
+		{
+			test: "progress deadline exceeded",
+			deployment: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Status: "False", Reason: progressDeadlineExceeded},
+				},
+			}),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			complete, err := deploymentRolloutComplete(tc.deployment)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error %t but got %v", tc.expectError, err)
+				return
+			}
+			if complete != tc.complete {
+				t.Errorf("expected complete %t but got %t", tc.complete, complete)
+			}
+		})
+	}
+}
+
+func TestStatefulSetRolloutComplete(t *testing.T) {
+	t.Parallel()
+
+	newStatefulSet := func(partition *int32, status appsv1.StatefulSetStatus) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Generation: 2},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: ptr.To(int32(3)),
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: partition},
+				},
+			},
+			Status: status,
+		}
+	}
+
+	testCases := []struct {
+		test        string
+		statefulSet *appsv1.StatefulSet
+		expectError bool
+		complete    bool
+	}{
+		{
+			test: "replicas not ready",
+			statefulSet: newStatefulSet(nil, appsv1.StatefulSetStatus{
+				ObservedGeneration: 2, ReadyReplicas: 2, CurrentRevision: "v2", UpdateRevision: "v2",
+			}),
+		},
+		{
+			test: "revision not updated",
+			statefulSet: newStatefulSet(nil, appsv1.StatefulSetStatus{
+				ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "v1", UpdateRevision: "v2",
+			}),
+		},
+		{
+			test: "complete",
+			statefulSet: newStatefulSet(nil, appsv1.StatefulSetStatus{
+				ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "v2", UpdateRevision: "v2",
+			}),
+			complete: true,
+		},
+		{
+			test: "partitioned update pending",
+			statefulSet: newStatefulSet(ptr.To(int32(1)), appsv1.StatefulSetStatus{
+				ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 1, CurrentRevision: "v1", UpdateRevision: "v2",
+			}),
+		},
+		{
+			test: "partitioned update complete",
+			statefulSet: newStatefulSet(ptr.To(int32(1)), appsv1.StatefulSetStatus{
+				ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 2, CurrentRevision: "v1", UpdateRevision: "v2",
+			}),
+			complete: true,
+		},
+		{
+			test: "on delete strategy",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			complete, err := statefulSetRolloutComplete(tc.statefulSet)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error %t but got %v", tc.expectError, err)
+				return
+			}
+			if complete != tc.complete {
+				t.Errorf("expected complete %t but got %t", tc.complete, complete)
+			}
+		})
+	}
+}
+
+func TestDaemonSetRolloutComplete(t *testing.T) {
+	t.Parallel()
+
+	newDaemonSet := func(status appsv1.DaemonSetStatus) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Generation: 2},
+			Spec: appsv1.DaemonSetSpec{
+				UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType},
+			},
+			Status: status,
+		}
+	}
+
+	testCases := []struct {
+		test      string
+		daemonSet *appsv1.DaemonSet
+		complete  bool
+	}{
+		{
+			test: "pods not updated",
+			daemonSet: newDaemonSet(appsv1.DaemonSetStatus{
+				ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3,
+			}),
+		},
+		{
+			test: "pods unavailable",
+			daemonSet: newDaemonSet(appsv1.DaemonSetStatus{
+				ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3,
+				NumberUnavailable: 1,
+			}),
+		},
+		{
+			test: "complete",
+			daemonSet: newDaemonSet(appsv1.DaemonSetStatus{
+				ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3,
+			}),
+			complete: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			complete, err := daemonSetRolloutComplete(tc.daemonSet)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if complete != tc.complete {
+				t.Errorf("expected complete %t but got %t", tc.complete, complete)
+			}
+		})
+	}
+}
+
+func TestWaitRolloutComplete(t *testing.T) {
+	t.Parallel()
+	type TestCase struct {
+		test           string
+		kind           string
+		updated        *appsv1.Deployment
+		expectError    bool
+		expectedResult bool
+		timeout        int64
+	}
+
+	testCases := []TestCase{
+		{
+			test: "rollout completed before timeout",
+			kind: "Deployment",
+			updated: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3,
+			}),
+			expectedResult: true,
+			timeout:        5,
+		},
+		{
+			test: "timeout waiting for rollout",
+			kind: "deployments.apps",
+			updated: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3,
+			}),
+			expectedResult: false,
+			timeout:        2,
+		},
+		{
+			test: "progress deadline exceeded",
+			kind: "Deployment",
+			updated: newDeployment(3, appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Status: "False", Reason: progressDeadlineExceeded},
+				},
+			}),
+			expectError: true,
+			timeout:     5,
+		},
+		{
+			test:        "kind not supported",
+			kind:        "Pod",
+			expectError: true,
+			timeout:     5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, _ := testutils.NewFakeDynamic()
+			client := resources.NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+			clientset := testutils.NewFakeClientset()
+			h := NewHelper(context.TODO(), clientset, client, nil, "default")
+
+			_, err := client.Structured().Create(newDeployment(3, appsv1.DeploymentStatus{ObservedGeneration: 1}))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if tc.updated != nil {
+				go func(tc TestCase) {
+					time.Sleep(500 * time.Millisecond)
+					obj, e := utils.RuntimeToGeneric(tc.updated)
+					if e == nil {
+						_, e = client.Update(obj)
+					}
+					if e != nil {
+						t.Errorf("unexpected error: %v", e)
+					}
+				}(tc)
+			}
+
+			result, err := h.WaitRolloutComplete(tc.kind, "nginx", tc.timeout)
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if tc.expectError && err == nil {
+				t.Error("expected an error but none returned")
+				return
+			}
+			if result != tc.expectedResult {
+				t.Errorf("expected result %t but %t returned", tc.expectedResult, result)
+			}
+		})
+	}
+}
+
+func newPodTemplate(image string, labels map[string]string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		},
+	}
+}
+
+func controllerRef(kind string, name string, uid types.UID) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: ptr.To(true)},
+	}
+}
+
+func newReplicaSet(name string, revision string, owner types.UID, image string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "nginx"},
+			Annotations:     map[string]string{revisionAnnotation: revision, changeCauseAnnotation: "image " + image},
+			OwnerReferences: controllerRef("Deployment", "nginx", owner),
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Template: newPodTemplate(image, map[string]string{"app": "nginx", "pod-template-hash": name}),
+		},
+	}
+}
+
+func newControllerRevision(name string, revision int64, owner types.UID, image string) *appsv1.ControllerRevision {
+	return &appsv1.ControllerRevision{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ControllerRevision"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "db"},
+			OwnerReferences: controllerRef("StatefulSet", "db", owner),
+		},
+		Data: runtime.RawExtension{
+			Raw: []byte(`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"db"}},` +
+				`"spec":{"containers":[{"name":"app","image":"` + image + `"}]}}}}`),
+		},
+		Revision: revision,
+	}
+}
+
+// newRolloutFixture creates a Deployment with two revisions and a StatefulSet with two revisions, as well as
+// revisions not controlled by them
+func newRolloutFixture(t *testing.T) (*helpers, *resources.Client) {
+	t.Helper()
+
+	fake, _ := testutils.NewFakeDynamic()
+	client := resources.NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+	clientset := testutils.NewFakeClientset()
+	h := &helpers{client: client, clientset: clientset, ctx: context.TODO(), namespace: "default"}
+
+	selector := func(app string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+	}
+
+	objs := []interface{}{
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", UID: "deployment-uid"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: selector("nginx"),
+				Template: newPodTemplate("nginx:1.2", map[string]string{"app": "nginx"}),
+			},
+		},
+		newReplicaSet("nginx-2", "2", "deployment-uid", "nginx:1.2"),
+		newReplicaSet("nginx-1", "1", "deployment-uid", "nginx:1.1"),
+		newReplicaSet("other-1", "3", "other-uid", "nginx:1.3"),
+		&appsv1.StatefulSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", UID: "statefulset-uid"},
+			Spec: appsv1.StatefulSetSpec{
+				Selector: selector("db"),
+				Template: newPodTemplate("postgres:16", map[string]string{"app": "db"}),
+			},
+		},
+		newControllerRevision("db-1", 1, "statefulset-uid", "postgres:15"),
+		newControllerRevision("db-2", 2, "statefulset-uid", "postgres:16"),
+	}
+	for _, obj := range objs {
+		if _, err := client.Structured().Create(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return h, client
+}
+
+// templateImage returns the image of the first container in the pod template of a workload
+func templateImage(t *testing.T, obj map[string]interface{}) string {
+	t.Helper()
+
+	containers, _, _ := unstructured.NestedSlice(obj, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		t.Fatalf("object has no containers")
+	}
+	image, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "image")
+	return image
+}
+
+func TestRolloutHistory(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test          string
+		kind          string
+		name          string
+		expectError   bool
+		expectedNames []string
+	}{
+		{
+			test:          "deployment",
+			kind:          "Deployment",
+			name:          "nginx",
+			expectedNames: []string{"nginx-1", "nginx-2"},
+		},
+		{
+			test:          "statefulset",
+			kind:          "StatefulSet",
+			name:          "db",
+			expectedNames: []string{"db-1", "db-2"},
+		},
+		{
+			test:        "workload not found",
+			kind:        "Deployment",
+			name:        "missing",
+			expectError: true,
+		},
+		{
+			test:        "kind not supported",
+			kind:        "ConfigMap",
+			name:        "nginx",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			h, _ := newRolloutFixture(t)
+			history, err := h.RolloutHistory(tc.kind, tc.name)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error %t but got %v", tc.expectError, err)
+				return
+			}
+
+			names := []string{}
+			for i, revision := range history {
+				if revision.Revision != int64(i+1) {
+					t.Errorf("expected revision %d but got %d", i+1, revision.Revision)
+				}
+				if _, found := revision.Template["$patch"]; found {
+					t.Errorf("unexpected $patch directive in template of revision %d", revision.Revision)
+				}
+				names = append(names, revision.Name)
+			}
+			if !tc.expectError && !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("expected revisions %v but got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestRolloutUndo(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test          string
+		kind          string
+		name          string
+		revision      int64
+		expectError   bool
+		expectedImage string
+	}{
+		{
+			test:          "deployment to previous revision",
+			kind:          "Deployment",
+			name:          "nginx",
+			expectedImage: "nginx:1.1",
+		},
+		{
+			test:          "deployment to given revision",
+			kind:          "Deployment",
+			name:          "nginx",
+			revision:      2,
+			expectedImage: "nginx:1.2",
+		},
+		{
+			test:          "statefulset to previous revision",
+			kind:          "StatefulSet",
+			name:          "db",
+			expectedImage: "postgres:15",
+		},
+		{
+			test:        "revision of another workload",
+			kind:        "Deployment",
+			name:        "nginx",
+			revision:    3,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			h, _ := newRolloutFixture(t)
+			obj, err := h.RolloutUndo(tc.kind, tc.name, tc.revision)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error %t but got %v", tc.expectError, err)
+				return
+			}
+			if tc.expectError {
+				return
+			}
+
+			if image := templateImage(t, obj); image != tc.expectedImage {
+				t.Errorf("expected image %q but got %q", tc.expectedImage, image)
+			}
+			labels, _, _ := unstructured.NestedStringMap(obj, "spec", "template", "metadata", "labels")
+			if _, found := labels[appsv1.DefaultDeploymentUniqueLabelKey]; found {
+				t.Errorf("unexpected %s label in template", appsv1.DefaultDeploymentUniqueLabelKey)
+			}
+		})
+	}
+}
+
+func TestRolloutRestartAndPause(t *testing.T) {
+	t.Parallel()
+
+	h, _ := newRolloutFixture(t)
+
+	obj, err := h.RolloutRestart("StatefulSet", "db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	annotations, _, _ := unstructured.NestedStringMap(obj, "spec", "template", "metadata", "annotations")
+	if _, err = time.Parse(time.RFC3339, annotations[restartedAtAnnotation]); err != nil {
+		t.Errorf("expected %s annotation with a timestamp: %v", restartedAtAnnotation, err)
+	}
+	if image := templateImage(t, obj); image != "postgres:16" {
+		t.Errorf("expected the template to be preserved but image is %q", image)
+	}
+
+	obj, err = h.RolloutPause("Deployment", "nginx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paused, _, _ := unstructured.NestedBool(obj, "spec", "paused"); !paused {
+		t.Errorf("expected deployment to be paused")
+	}
+
+	if _, err = h.RolloutRestart("Deployment", "nginx"); err == nil {
+		t.Errorf("expected an error restarting a paused deployment")
+	}
+	if _, err = h.RolloutUndo("Deployment", "nginx", 0); err == nil {
+		t.Errorf("expected an error rolling back a paused deployment")
+	}
+
+	obj, err = h.RolloutResume("Deployment", "nginx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paused, _, _ := unstructured.NestedBool(obj, "spec", "paused"); paused {
+		t.Errorf("expected deployment to be resumed")
+	}
+
+	if _, err = h.RolloutRestart("Deployment", "nginx"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err = h.RolloutPause("StatefulSet", "db"); err == nil {
+		t.Errorf("expected an error pausing a statefulset")
+	}
+}
@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// defaultWaitTimeout is the time WaitFor waits if no timeout is given, as in kubectl wait
+const defaultWaitTimeout = 30 * time.Second
+
+// WaitHelper defines helper functions for waiting on objects of any kind
+type WaitHelper interface {
+	// WaitFor waits for the named object of the kind to match all the conditions given in the options and
+	// returns it. If no condition is given, it waits for the object to exist. If the timeout expires an error
+	// describing the last observed state of the object is returned.
+	WaitFor(kind string, name string, options ...WaitForOptions) (map[string]interface{}, error)
+}
+
+// WaitForOptions defines the conditions an object is waited for
+type WaitForOptions struct {
+	// Condition is the type of a status condition that must be "True" (e.g. "Ready"). Another status can be
+	// given after an equal sign (e.g. "Ready=False")
+	Condition string `js:"condition"`
+	// JSONPath is a field of the object (e.g. "{.status.phase}") that must have the value in Equals. If
+	// Equals is empty, the field must not be empty
+	JSONPath string `js:"jsonpath"`
+	// Equals is the value of the field given in JSONPath
+	Equals string `js:"equals"`
+	// Predicate is a function that receives the object and returns true when it matches
+	Predicate func(obj map[string]interface{}) (bool, error) `js:"predicate"`
+	// Timeout is the maximum time to wait in seconds. Defaults to 30 seconds
+	Timeout int64 `js:"timeout"`
+}
+
+// objectMatcher checks if an object matches the conditions waited for
+type objectMatcher func(obj map[string]interface{}) (bool, error)
+
+// matcher returns a function that checks if an object matches all the conditions in the options
+func (o WaitForOptions) matcher() (objectMatcher, error) {
+	matchers := []objectMatcher{}
+
+	if o.Condition != "" {
+		conditionType, status, found := strings.Cut(o.Condition, "=")
+		if !found {
+			status = "True"
+		}
+		matchers = append(matchers, func(obj map[string]interface{}) (bool, error) {
+			return hasCondition(obj, conditionType, status)
+		})
+	}
+
+	if o.Equals != "" && o.JSONPath == "" {
+		return nil, errors.New("equals requires a jsonpath")
+	}
+	if o.JSONPath != "" {
+		path, err := parseJSONPath(o.JSONPath)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, func(obj map[string]interface{}) (bool, error) {
+			return matchesJSONPath(path, obj, o.Equals)
+		})
+	}
+
+	if o.Predicate != nil {
+		matchers = append(matchers, o.Predicate)
+	}
+
+	return func(obj map[string]interface{}) (bool, error) {
+		for _, match := range matchers {
+			matched, err := match(obj)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// hasCondition returns true if the object has a status condition of the given type and status. The status
+// is compared ignoring case
+func hasCondition(obj map[string]interface{}, conditionType string, status string) (bool, error) {
+	conditions, _, err := unstructured.NestedSlice(obj, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range conditions {
+		condition, isMap := c.(map[string]interface{})
+		if !isMap || !strings.EqualFold(fmt.Sprint(condition["type"]), conditionType) {
+			continue
+		}
+		return strings.EqualFold(fmt.Sprint(condition["status"]), status), nil
+	}
+	return false, nil
+}
+
+// parseJSONPath parses a JSONPath template. The enclosing braces are optional (e.g. ".status.phase")
+func parseJSONPath(template string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(template, "{") {
+		template = "{" + template + "}"
+	}
+
+	path := jsonpath.New("waitFor").AllowMissingKeys(true)
+	if err := path.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath %q: %w", template, err)
+	}
+	return path, nil
+}
+
+// matchesJSONPath returns true if the field given by the path has the expected value or, if no value is
+// expected, if the field is not empty
+func matchesJSONPath(path *jsonpath.JSONPath, obj map[string]interface{}, expected string) (bool, error) {
+	results, err := path.FindResults(obj)
+	if err != nil {
+		return false, err
+	}
+
+	values := []string{}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() && value.Interface() != nil {
+				values = append(values, fmt.Sprint(value.Interface()))
+			}
+		}
+	}
+
+	if expected == "" {
+		return len(values) > 0 && values[0] != "", nil
+	}
+	return len(values) == 1 && values[0] == expected, nil
+}
+
+func (h *helpers) WaitFor(kind string, name string, options ...WaitForOptions) (map[string]interface{}, error) {
+	var opts WaitForOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	match, err := opts.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultWaitTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+
+	obj, last, err := h.watchUntil(kind, name, match, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, waitTimeoutError(kind, name, last)
+	}
+	return obj, nil
+}
+
+// watchUntil watches the named object until it matches or the timeout expires. Returns the matched object
+// or, if the timeout expires, nil and the last observed state of the object (nil if it was not found)
+func (h *helpers) watchUntil(
+	kind string,
+	name string,
+	match objectMatcher,
+	timeout time.Duration,
+) (map[string]interface{}, map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(h.ctx, timeout)
+	defer cancel()
+
+	// the current state is checked first and the changes are watched from its resource version
+	var last map[string]interface{}
+	watchOptions := resources.WatchOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+	obj, err := h.client.Get(kind, name, h.namespace)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, nil, err
+	default:
+		matched, err := match(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		if matched {
+			return obj, nil, nil
+		}
+		last = obj
+		watchOptions.ResourceVersion = (&unstructured.Unstructured{Object: obj}).GetResourceVersion()
+	}
+
+	watcher, err := h.client.Watch(kind, h.namespace, watchOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, last, nil
+		case event, ok := <-watcher.Events():
+			if !ok {
+				return nil, nil, fmt.Errorf("watch stopped waiting for %s %s", kind, name)
+			}
+
+			switch watch.EventType(event.Type) {
+			case watch.Added, watch.Modified:
+				if (&unstructured.Unstructured{Object: event.Object}).GetName() != name {
+					continue
+				}
+				matched, err := match(event.Object)
+				if err != nil {
+					return nil, nil, err
+				}
+				if matched {
+					return event.Object, nil, nil
+				}
+				last = event.Object
+			case watch.Deleted:
+				if (&unstructured.Unstructured{Object: event.Object}).GetName() == name {
+					last = nil
+				}
+			case watch.Error:
+				return nil, nil, apierrors.FromObject(&unstructured.Unstructured{Object: event.Object})
+			default:
+			}
+		}
+	}
+}
+
+// waitTimeoutError returns the error for a wait that expired, describing the last observed state of the object
+func waitTimeoutError(kind string, name string, last map[string]interface{}) error {
+	if last == nil {
+		return fmt.Errorf("timeout waiting for %s %s: object not found", kind, name)
+	}
+
+	status, found := last["status"]
+	if !found {
+		return fmt.Errorf("timeout waiting for %s %s: object has no status", kind, name)
+	}
+	observed, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("timeout waiting for %s %s", kind, name)
+	}
+	return fmt.Errorf("timeout waiting for %s %s: last observed status: %s", kind, name, observed)
+}
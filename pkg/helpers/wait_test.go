@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/internal/testutils"
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+func newPVC(name string, status map[string]interface{}) map[string]interface{} {
+	pvc := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+	}
+	if status != nil {
+		pvc["status"] = status
+	}
+	return pvc
+}
+
+func TestWaitFor(t *testing.T) {
+	t.Parallel()
+	type TestCase struct {
+		test          string
+		initial       map[string]interface{}
+		updated       map[string]interface{}
+		options       WaitForOptions
+		expectError   bool
+		errorContains string
+	}
+
+	testCases := []TestCase{
+		{
+			test:    "jsonpath equals",
+			initial: newPVC("data", map[string]interface{}{"phase": "Pending"}),
+			updated: newPVC("data", map[string]interface{}{"phase": "Bound"}),
+			options: WaitForOptions{JSONPath: "{.status.phase}", Equals: "Bound", Timeout: 5},
+		},
+		{
+			test:    "jsonpath without braces",
+			initial: newPVC("data", map[string]interface{}{"phase": "Pending"}),
+			updated: newPVC("data", map[string]interface{}{"phase": "Bound"}),
+			options: WaitForOptions{JSONPath: ".status.phase", Equals: "Bound", Timeout: 5},
+		},
+		{
+			test:    "jsonpath not empty",
+			initial: newPVC("data", nil),
+			updated: newPVC("data", map[string]interface{}{"capacity": map[string]interface{}{"storage": "1Gi"}}),
+			options: WaitForOptions{JSONPath: "{.status.capacity.storage}", Timeout: 5},
+		},
+		{
+			test:    "condition",
+			initial: newPVC("data", nil),
+			updated: newPVC("data", map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Resizing", "status": "True"},
+				},
+			}),
+			options: WaitForOptions{Condition: "Resizing", Timeout: 5},
+		},
+		{
+			test:    "condition with status",
+			initial: newPVC("data", nil),
+			updated: newPVC("data", map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Resizing", "status": "False"},
+				},
+			}),
+			options: WaitForOptions{Condition: "Resizing=false", Timeout: 5},
+		},
+		{
+			test:    "predicate",
+			initial: newPVC("data", map[string]interface{}{"phase": "Pending"}),
+			updated: newPVC("data", map[string]interface{}{"phase": "Bound"}),
+			options: WaitForOptions{
+				Predicate: func(obj map[string]interface{}) (bool, error) {
+					status, _ := obj["status"].(map[string]interface{})
+					return status["phase"] == "Bound", nil
+				},
+				Timeout: 5,
+			},
+		},
+		{
+			test:    "object created",
+			updated: newPVC("data", nil),
+			options: WaitForOptions{Timeout: 5},
+		},
+		{
+			test:          "timeout reports last observed state",
+			initial:       newPVC("data", map[string]interface{}{"phase": "Pending"}),
+			updated:       newPVC("data", map[string]interface{}{"phase": "Lost"}),
+			options:       WaitForOptions{JSONPath: "{.status.phase}", Equals: "Bound", Timeout: 2},
+			expectError:   true,
+			errorContains: `last observed status: {"phase":"Lost"}`,
+		},
+		{
+			test:          "timeout waiting for object",
+			options:       WaitForOptions{Timeout: 1},
+			expectError:   true,
+			errorContains: "object not found",
+		},
+		{
+			test:          "equals requires jsonpath",
+			options:       WaitForOptions{Equals: "Bound"},
+			expectError:   true,
+			errorContains: "requires a jsonpath",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, _ := testutils.NewFakeDynamic()
+			client := resources.NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+			clientset := testutils.NewFakeClientset()
+			h := NewHelper(context.TODO(), clientset, client, nil, "default")
+
+			if tc.initial != nil {
+				_, err := client.Create(tc.initial)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+
+			if tc.updated != nil {
+				go func(tc TestCase) {
+					time.Sleep(500 * time.Millisecond)
+					var e error
+					if tc.initial != nil {
+						_, e = client.Update(tc.updated)
+					} else {
+						_, e = client.Create(tc.updated)
+					}
+					if e != nil {
+						t.Errorf("unexpected error: %v", e)
+					}
+				}(tc)
+			}
+
+			obj, err := h.WaitFor("PersistentVolumeClaim", "data", tc.options)
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error but none returned")
+					return
+				}
+				if !strings.Contains(err.Error(), tc.errorContains) {
+					t.Errorf("expected error containing %q but got %q", tc.errorContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if obj == nil {
+				t.Errorf("expected the matched object")
+			}
+		})
+	}
+}
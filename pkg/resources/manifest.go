@@ -0,0 +1,164 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DocumentError reports the failure to process one of the documents of a manifest
+type DocumentError struct {
+	// Index of the document in the manifest, starting at 0
+	Index int
+	// Kind of the object described in the document, if known
+	Kind string
+	// Name of the object described in the document, if known
+	Name string
+	Err  error
+}
+
+func (e *DocumentError) Error() string {
+	if e.Kind == "" {
+		return fmt.Sprintf("document %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("document %d (%s %s): %v", e.Index, e.Kind, e.Name, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyError reports the documents of a manifest that failed to be applied, along with the objects applied
+// from the other documents
+type ApplyError struct {
+	// Applied are the objects applied from the documents that did not fail
+	Applied []map[string]interface{}
+	// Errors of the documents that failed
+	Errors []*DocumentError
+}
+
+func (e *ApplyError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *ApplyError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// document holds an object decoded from a document in a manifest
+type document struct {
+	index int
+	obj   *unstructured.Unstructured
+	gvk   *schema.GroupVersionKind
+}
+
+// applyOrder defines the order in which kinds are applied, so objects are created before the
+// objects that depend on them. Kinds not in the list are applied last.
+func applyOrder() []string {
+	return []string{
+		"Namespace",
+		"NetworkPolicy",
+		"ResourceQuota",
+		"LimitRange",
+		"PodDisruptionBudget",
+		"ServiceAccount",
+		"Secret",
+		"ConfigMap",
+		"StorageClass",
+		"PersistentVolume",
+		"PersistentVolumeClaim",
+		"CustomResourceDefinition",
+		"ClusterRole",
+		"ClusterRoleBinding",
+		"Role",
+		"RoleBinding",
+		"Service",
+		"DaemonSet",
+		"Pod",
+		"ReplicationController",
+		"ReplicaSet",
+		"Deployment",
+		"HorizontalPodAutoscaler",
+		"StatefulSet",
+		"Job",
+		"CronJob",
+		"IngressClass",
+		"Ingress",
+		"APIService",
+	}
+}
+
+// kindPriority returns the position of the kind in the apply order
+func kindPriority(kind string) int {
+	order := applyOrder()
+	for i, k := range order {
+		if k == kind {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// decodeManifest decodes the objects described in the (possibly multi-document) YAML manifest
+// and returns them sorted in the order they must be applied, along with the errors of the documents
+// that could not be decoded. Empty documents are ignored. An error is returned only if the manifest
+// cannot be split in documents.
+func (c *Client) decodeManifest(manifest string) ([]document, []*DocumentError, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifest)))
+
+	docs := []document{}
+	errs := []*DocumentError{}
+	for index := 0; ; index++ {
+		data, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		if isEmptyDocument(data) {
+			continue
+		}
+
+		uObj := &unstructured.Unstructured{}
+		_, gvk, err := c.serializer.Decode(data, nil, uObj)
+		if err != nil {
+			errs = append(errs, &DocumentError{Index: index, Err: fmt.Errorf("failed to decode manifest: %w", err)})
+			continue
+		}
+		docs = append(docs, document{index: index, obj: uObj, gvk: gvk})
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return kindPriority(docs[i].gvk.Kind) < kindPriority(docs[j].gvk.Kind)
+	})
+
+	return docs, errs, nil
+}
+
+// isEmptyDocument checks if a YAML document has no content other than comments and whitespace
+func isEmptyDocument(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,274 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fieldManager is the default name of the actor applying changes to resources
+const fieldManager = "xk6-kubernetes"
+
+// ListOptions defines the options for filtering and paginating the objects returned by List
+type ListOptions struct {
+	LabelSelector   string `js:"labelSelector"`   // selector restricting the objects returned by their labels
+	FieldSelector   string `js:"fieldSelector"`   // selector restricting the objects returned by their fields
+	Limit           int64  `js:"limit"`           // maximum number of objects to return
+	Continue        string `js:"continue"`        // token returned by a previous page for retrieving the next one
+	ResourceVersion string `js:"resourceVersion"` // resource version the request is served from
+}
+
+// Page contains a chunk of objects returned by a paginated List
+type Page struct {
+	// Items in the page
+	Items []map[string]interface{} `js:"items"`
+	// Continue is the token for retrieving the next page. Empty if there are no more pages
+	Continue string `js:"continue"`
+	// ResourceVersion of the list
+	ResourceVersion string `js:"resourceVersion"`
+	// RemainingItemCount is the estimated number of items not yet returned, if known
+	RemainingItemCount *int64 `js:"remainingItemCount"`
+}
+
+// toListOptions returns the ListOptions for the api request from the (optional) list options
+func toListOptions(options []ListOptions) metav1.ListOptions {
+	if len(options) == 0 {
+		return metav1.ListOptions{}
+	}
+	o := options[0]
+	return metav1.ListOptions{
+		LabelSelector:   o.LabelSelector,
+		FieldSelector:   o.FieldSelector,
+		Limit:           o.Limit,
+		Continue:        o.Continue,
+		ResourceVersion: o.ResourceVersion,
+	}
+}
+
+// PatchOptions defines the options for patching a resource
+type PatchOptions struct {
+	// Type of patch: "json", "merge", "strategic", "apply" or "apply-cbor". Patch content types
+	// (e.g. "application/json-patch+json") are also accepted. Defaults to "merge"
+	Type string `js:"type"`
+	// Subresource to patch (e.g. "status" or "scale"). If empty, the main resource is patched
+	Subresource string `js:"subresource"`
+	// FieldManager is the name of the actor making the changes. Defaults to "xk6-kubernetes" for apply patches
+	FieldManager string `js:"fieldManager"`
+	// Force re-acquires the conflicting fields owned by other managers. Only valid for apply patches
+	Force bool `js:"force"`
+	// DryRun indicates the modifications should not be persisted
+	DryRun bool `js:"dryRun"`
+	// FieldValidation instructs the server how to handle unknown or duplicate fields:
+	// "Ignore", "Warn" or "Strict". If empty, the server's default is used
+	FieldValidation string `js:"fieldValidation"`
+}
+
+// patchType returns the patch type given its name or content type
+func (o PatchOptions) patchType() (types.PatchType, error) {
+	switch strings.ToLower(o.Type) {
+	case "", "merge", string(types.MergePatchType):
+		return types.MergePatchType, nil
+	case "json", string(types.JSONPatchType):
+		return types.JSONPatchType, nil
+	case "strategic", string(types.StrategicMergePatchType):
+		return types.StrategicMergePatchType, nil
+	case "apply", string(types.ApplyYAMLPatchType):
+		return types.ApplyYAMLPatchType, nil
+	case "apply-cbor", string(types.ApplyCBORPatchType):
+		return types.ApplyCBORPatchType, nil
+	default:
+		return "", fmt.Errorf("unsupported patch type: %q", o.Type)
+	}
+}
+
+// toPatchOptions returns the PatchOptions for the api request given the type of patch
+func (o PatchOptions) toPatchOptions(pt types.PatchType) (metav1.PatchOptions, error) {
+	validation, err := fieldValidation(o.FieldValidation)
+	if err != nil {
+		return metav1.PatchOptions{}, err
+	}
+
+	options := metav1.PatchOptions{
+		FieldManager:    o.FieldManager,
+		FieldValidation: validation,
+	}
+	if pt == types.ApplyYAMLPatchType || pt == types.ApplyCBORPatchType {
+		if options.FieldManager == "" {
+			options.FieldManager = fieldManager
+		}
+		options.Force = &o.Force
+	}
+	if o.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options, nil
+}
+
+// ApplyOptions defines the options for applying resources
+type ApplyOptions struct {
+	// Force re-acquires the conflicting fields owned by other managers
+	Force bool `js:"force"`
+	// FieldManager is the name of the actor applying the changes. Defaults to "xk6-kubernetes"
+	FieldManager string `js:"fieldManager"`
+	// DryRun indicates the modifications should not be persisted
+	DryRun bool `js:"dryRun"`
+	// FieldValidation instructs the server how to handle unknown or duplicate fields:
+	// "Ignore", "Warn" or "Strict". If empty, the server's default is used
+	FieldValidation string `js:"fieldValidation"`
+}
+
+// toApplyOptions returns the PatchOptions for the apply request from the (optional) apply options.
+// Apply options are sent as patch options because metav1.ApplyOptions does not support field validation
+func toApplyOptions(options []ApplyOptions) (metav1.PatchOptions, error) {
+	var o ApplyOptions
+	if len(options) > 0 {
+		o = options[0]
+	}
+
+	return PatchOptions{
+		Type:            "apply",
+		FieldManager:    o.FieldManager,
+		Force:           o.Force,
+		DryRun:          o.DryRun,
+		FieldValidation: o.FieldValidation,
+	}.toPatchOptions(types.ApplyPatchType)
+}
+
+// fieldValidation returns the field validation directive given its case-insensitive name
+func fieldValidation(validation string) (string, error) {
+	switch strings.ToLower(validation) {
+	case "":
+		return "", nil
+	case "ignore":
+		return metav1.FieldValidationIgnore, nil
+	case "warn":
+		return metav1.FieldValidationWarn, nil
+	case "strict":
+		return metav1.FieldValidationStrict, nil
+	default:
+		return "", fmt.Errorf("invalid field validation %q: must be one of Ignore, Warn or Strict", validation)
+	}
+}
+
+// Preconditions must be fulfilled before a resource is deleted
+type Preconditions struct {
+	// UID of the resource
+	UID string `js:"uid"`
+	// ResourceVersion of the resource
+	ResourceVersion string `js:"resourceVersion"`
+}
+
+// DeleteOptions defines the options for deleting resources
+type DeleteOptions struct {
+	// GracePeriodSeconds is the duration in seconds before the resource is deleted. Zero deletes immediately.
+	// If not set, the default grace period for the kind of resource is used
+	GracePeriodSeconds *int64 `js:"gracePeriodSeconds"`
+	// PropagationPolicy determines how dependents are garbage collected: "Foreground", "Background" or "Orphan"
+	PropagationPolicy string `js:"propagationPolicy"`
+	// Preconditions that must be fulfilled before the resource is deleted
+	Preconditions *Preconditions `js:"preconditions"`
+	// DryRun indicates the modifications should not be persisted
+	DryRun bool `js:"dryRun"`
+	// Wait is the number of seconds to wait for the resource to be gone. If zero, it does not wait
+	Wait int64 `js:"wait"`
+}
+
+// toDeleteOptions returns the DeleteOptions for the api request
+func (o DeleteOptions) toDeleteOptions() (metav1.DeleteOptions, error) {
+	options := metav1.DeleteOptions{
+		GracePeriodSeconds: o.GracePeriodSeconds,
+	}
+
+	if o.PropagationPolicy != "" {
+		policy, err := propagationPolicy(o.PropagationPolicy)
+		if err != nil {
+			return metav1.DeleteOptions{}, err
+		}
+		options.PropagationPolicy = &policy
+	}
+
+	if o.Preconditions != nil {
+		options.Preconditions = &metav1.Preconditions{}
+		if o.Preconditions.UID != "" {
+			uid := types.UID(o.Preconditions.UID)
+			options.Preconditions.UID = &uid
+		}
+		if o.Preconditions.ResourceVersion != "" {
+			options.Preconditions.ResourceVersion = &o.Preconditions.ResourceVersion
+		}
+	}
+
+	if o.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options, nil
+}
+
+// propagationPolicy returns the deletion propagation policy given its case-insensitive name
+func propagationPolicy(policy string) (metav1.DeletionPropagation, error) {
+	switch strings.ToLower(policy) {
+	case "foreground":
+		return metav1.DeletePropagationForeground, nil
+	case "background":
+		return metav1.DeletePropagationBackground, nil
+	case "orphan":
+		return metav1.DeletePropagationOrphan, nil
+	default:
+		return "", fmt.Errorf("invalid propagation policy %q: must be one of Foreground, Background or Orphan", policy)
+	}
+}
+
+// DeleteCollectionOptions defines the options for deleting a collection of resources
+type DeleteCollectionOptions struct {
+	// LabelSelector restricts the resources deleted by their labels
+	LabelSelector string `js:"labelSelector"`
+	// FieldSelector restricts the resources deleted by their fields
+	FieldSelector string `js:"fieldSelector"`
+	// PropagationPolicy determines how dependents are garbage collected: "Foreground", "Background" or "Orphan"
+	PropagationPolicy string `js:"propagationPolicy"`
+	// GracePeriodSeconds is the duration in seconds before the resources are deleted. Zero deletes immediately.
+	// If not set, the default grace period for the kind of resource is used
+	GracePeriodSeconds *int64 `js:"gracePeriodSeconds"`
+	// DryRun indicates the modifications should not be persisted
+	DryRun bool `js:"dryRun"`
+}
+
+// toListOptions returns the ListOptions for selecting the resources to delete
+func (o DeleteCollectionOptions) toListOptions() metav1.ListOptions {
+	return toListOptions([]ListOptions{{
+		LabelSelector: o.LabelSelector,
+		FieldSelector: o.FieldSelector,
+	}})
+}
+
+// toDeleteOptions returns the DeleteOptions for the api request
+func (o DeleteCollectionOptions) toDeleteOptions() (metav1.DeleteOptions, error) {
+	return DeleteOptions{
+		GracePeriodSeconds: o.GracePeriodSeconds,
+		PropagationPolicy:  o.PropagationPolicy,
+		DryRun:             o.DryRun,
+	}.toDeleteOptions()
+}
+
+// WatchOptions defines the options for watching resources
+type WatchOptions struct {
+	// LabelSelector restricts the resources watched by their labels
+	LabelSelector string `js:"labelSelector"`
+	// FieldSelector restricts the resources watched by their fields
+	FieldSelector string `js:"fieldSelector"`
+	// ResourceVersion to start watching from. If empty, the watch starts with the current state
+	// of the resources, which is received as ADDED events
+	ResourceVersion string `js:"resourceVersion"`
+}
+
+// toListOptions returns the ListOptions for the watch request
+func (o WatchOptions) toListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector:       o.LabelSelector,
+		FieldSelector:       o.FieldSelector,
+		ResourceVersion:     o.ResourceVersion,
+		AllowWatchBookmarks: true,
+	}
+}
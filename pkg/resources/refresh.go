@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// SharedRESTMapper is a RESTMapper shared by many clients whose concurrent refreshes triggered by unknown
+// kinds are deduplicated. As the RESTMapper and its discovery cache can be shared by many clients, this
+// prevents clients that find the same unknown kind at the same time from discarding the discovery once each.
+// Explicit refreshes with Reset are not deduplicated
+type SharedRESTMapper struct {
+	meta.RESTMapper
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewSharedRESTMapper returns a RESTMapper that deduplicates the concurrent refreshes of the given mapper
+func NewSharedRESTMapper(mapper meta.RESTMapper) *SharedRESTMapper {
+	return &SharedRESTMapper{
+		RESTMapper: mapper,
+	}
+}
+
+// Generation returns the number of times the mapper was reset. It must be taken before looking up a kind
+// for refreshing the mapper with ResetIfUnchanged if the kind is not found
+func (m *SharedRESTMapper) Generation() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.generation
+}
+
+// Reset discards the kinds known by the mapper
+func (m *SharedRESTMapper) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta.MaybeResetRESTMapper(m.RESTMapper)
+	m.generation++
+}
+
+// ResetIfUnchanged discards the kinds known by the mapper unless it was reset after the given generation
+// was taken, as the kinds are then already discovered again
+func (m *SharedRESTMapper) ResetIfUnchanged(generation uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.generation != generation {
+		return
+	}
+	meta.MaybeResetRESTMapper(m.RESTMapper)
+	m.generation++
+}
@@ -3,32 +3,74 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/xk6-kubernetes/pkg/utils"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	cbor "k8s.io/apimachinery/pkg/runtime/serializer/cbor/direct"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 )
 
+// deleteWorkers is the maximum number of objects deleted in parallel when deleting a collection one by one
+const deleteWorkers = 10
+
+// DefaultNamespace is the namespace used for the objects that do not specify one, unless the client is
+// configured with another namespace
+const DefaultNamespace = "default"
+
 // UnstructuredOperations defines generic functions that operate on any kind of Kubernetes object
 type UnstructuredOperations interface {
-	Apply(manifest string) error
+	Apply(manifest string, options ...ApplyOptions) ([]map[string]interface{}, error)
+	ApplyObject(obj map[string]interface{}, options ...ApplyOptions) (map[string]interface{}, error)
 	Create(obj map[string]interface{}) (map[string]interface{}, error)
-	Delete(kind string, name string, namespace string) error
+	Delete(kind string, name string, namespace string, options ...DeleteOptions) error
+	DeleteCollection(kind string, namespace string, options ...DeleteCollectionOptions) (int, error)
 	Get(kind string, name string, namespace string) (map[string]interface{}, error)
-	List(kind string, namespace string) ([]map[string]interface{}, error)
+	List(kind string, namespace string, options ...ListOptions) ([]map[string]interface{}, error)
+	ListPage(kind string, namespace string, options ...ListOptions) (*Page, error)
+	Patch(
+		kind string,
+		name string,
+		namespace string,
+		patch interface{},
+		options ...PatchOptions,
+	) (map[string]interface{}, error)
 	Update(obj map[string]interface{}) (map[string]interface{}, error)
 }
 
+// SubresourceOperations defines generic functions that operate on the subresources (e.g. "status" or "scale")
+// of any kind of Kubernetes object
+type SubresourceOperations interface {
+	GetSubresource(kind string, name string, namespace string, subresource string) (map[string]interface{}, error)
+	PatchSubresource(
+		kind string,
+		name string,
+		namespace string,
+		subresource string,
+		patch interface{},
+		options ...PatchOptions,
+	) (map[string]interface{}, error)
+	UpdateSubresource(kind string, subresource string, obj map[string]interface{}) (map[string]interface{}, error)
+}
+
 // StructuredOperations defines generic operations that handles runtime objects such as corev1.Pod.
 // It facilitates handling objects in the situations where their type is known as opposed to the
 // UnstructuredOperations
@@ -37,11 +79,15 @@ type StructuredOperations interface {
 	// The resource must be passed by value (e.g corev1.Pod) and a value (not a reference) will be returned
 	Create(obj interface{}) (interface{}, error)
 	// Delete deletes a resource given its kind, name and namespace
-	Delete(kind string, name string, namespace string) error
+	Delete(kind string, name string, namespace string, options ...DeleteOptions) error
 	// Get retrieves a resource into the given placeholder given its kind, name and namespace
 	Get(kind string, name string, namespace string, obj interface{}) error
-	// List retrieves a list of resources in the given slice given their kind and namespace
-	List(kind string, namespace string, list interface{}) error
+	// List retrieves a list of resources in the given slice given their kind and namespace.
+	// Optionally, the resources can be filtered and paginated using ListOptions
+	List(kind string, namespace string, list interface{}, options ...ListOptions) error
+	// Patch applies a patch to a resource given its kind, name and namespace and retrieves the patched
+	// resource into the given placeholder
+	Patch(kind string, name string, namespace string, patch interface{}, obj interface{}, options ...PatchOptions) error
 	// Update updates an existing resource and returns the updated version
 	// The resource must be passed by value (e.g corev1.Pod) and a value (not a reference) will be returned
 	Update(obj interface{}) (interface{}, error)
@@ -58,6 +104,8 @@ type Client struct {
 	dynamic    dynamic.Interface
 	mapper     meta.RESTMapper
 	serializer runtime.Serializer
+	// namespace is used for the objects that do not specify one
+	namespace string
 }
 
 // NewFromConfig creates a new Client using the provided kubernetes client configuration
@@ -76,6 +124,7 @@ func NewFromClient(ctx context.Context, dynamic dynamic.Interface) *Client {
 		ctx:        ctx,
 		dynamic:    dynamic,
 		serializer: yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme),
+		namespace:  DefaultNamespace,
 	}
 }
 
@@ -85,59 +134,235 @@ func (c *Client) WithMapper(mapper meta.RESTMapper) *Client {
 	return c
 }
 
-// getResource maps kinds to api resources
-func (c *Client) getResource(kind string, namespace string, versions ...string) (dynamic.ResourceInterface, error) {
-	gk := schema.ParseGroupKind(kind)
+// WithNamespace specifies the namespace for the objects that do not specify one
+func (c *Client) WithNamespace(namespace string) *Client {
+	c.namespace = namespace
+	return c
+}
+
+// RefreshDiscovery discards the kinds known by the client's RESTMapper, so they are discovered again
+// in the next request. Returns false if the RESTMapper cannot be reset
+func (c *Client) RefreshDiscovery() bool {
+	mapper, resettable := c.mapper.(meta.ResettableRESTMapper)
+	if !resettable {
+		return false
+	}
+	mapper.Reset()
+	return true
+}
+
+// HasKind returns true if the kind is served by the cluster. The kind can be qualified by its group
+// (e.g. "Deployment.apps"). Unlike other operations, the discovery is not refreshed if the kind is not
+// found, so checking for kinds the cluster does not serve is cheap. Kinds added after the discovery
+// (e.g. by creating a CRD) are found after calling RefreshDiscovery
+func (c *Client) HasKind(kind string) (bool, error) {
+	if c.mapper == nil {
+		return false, fmt.Errorf("RESTMapper not initialized")
+	}
+
+	_, err := c.resolveKind(kind)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MappingFor returns the mapping of the kind to its api resource, which describes its group, version,
+// resource name and whether it is namespaced
+func (c *Client) MappingFor(kind string) (*meta.RESTMapping, error) {
+	return c.restMapping(kind)
+}
+
+// restMapping returns the mapping of the kind to its api resource. If the versions are not given, the
+// kind can specify the version (e.g. "Deployment.v1.apps" or "apps/v1/Deployment") or otherwise the
+// preferred version is used. Resource names (e.g. "deployments" or "deploy") are also accepted
+func (c *Client) restMapping(kind string, versions ...string) (*meta.RESTMapping, error) {
 	if c.mapper == nil {
 		return nil, fmt.Errorf("RESTMapper not initialized")
 	}
 
+	refresh := c.discoveryRefresher()
+	mapping, err := c.resolveKind(kind, versions...)
+	// the kind may have been added after the mapper was populated (e.g. by creating a CRD)
+	if meta.IsNoMatchError(err) && refresh() {
+		mapping, err = c.resolveKind(kind, versions...)
+	}
+	return mapping, err
+}
+
+// discoveryRefresher returns a function that discards the kinds known by the RESTMapper after finding an
+// unknown kind and returns true if the kinds are discovered again. It must be called before looking up the
+// kind, so if the mapper is shared, the refresh is skipped when the mapper was refreshed concurrently
+func (c *Client) discoveryRefresher() func() bool {
+	shared, isShared := c.mapper.(*SharedRESTMapper)
+	if !isShared {
+		return c.RefreshDiscovery
+	}
+
+	generation := shared.Generation()
+	return func() bool {
+		shared.ResetIfUnchanged(generation)
+		return true
+	}
+}
+
+// resolveKind maps the kind to its api resource, following the conventions used by kubectl
+func (c *Client) resolveKind(kind string, versions ...string) (*meta.RESTMapping, error) {
+	if len(versions) == 0 {
+		// group/version/kind (e.g. "apps/v1/Deployment" or "v1/Pod")
+		if i := strings.LastIndex(kind, "/"); i > 0 {
+			gv, err := schema.ParseGroupVersion(kind[:i])
+			if err != nil {
+				return nil, err
+			}
+			return c.mapper.RESTMapping(gv.WithKind(kind[i+1:]).GroupKind(), gv.Version)
+		}
+
+		// kind.version.group (e.g. "Deployment.v1.apps"), which can also be a kind in a group with dots
+		gvk, _ := schema.ParseKindArg(kind)
+		if gvk != nil {
+			mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if !meta.IsNoMatchError(err) {
+				return mapping, err
+			}
+		}
+	}
+
+	gk := schema.ParseGroupKind(kind)
 	mapping, err := c.mapper.RESTMapping(gk, versions...)
+	if !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+
+	// resource name (e.g. "deployments", "deployment" or "deploy"), optionally with its group
+	gvk, resourceErr := c.mapper.KindFor(schema.ParseGroupResource(kind).WithVersion(""))
+	if meta.IsNoMatchError(resourceErr) {
+		// the error of the kind is more meaningful to the user
+		return nil, err
+	}
+	if resourceErr != nil {
+		return nil, resourceErr
+	}
+	if len(versions) == 0 {
+		versions = []string{gvk.Version}
+	}
+	return c.mapper.RESTMapping(gvk.GroupKind(), versions...)
+}
+
+// getResource maps kinds to api resources
+func (c *Client) getResource(kind string, namespace string, versions ...string) (dynamic.ResourceInterface, error) {
+	mapping, err := c.restMapping(kind, versions...)
 	if err != nil {
 		return nil, err
 	}
 
-	var resource dynamic.ResourceInterface
+	return c.mappedResource(mapping, namespace), nil
+}
+
+// mappedResource returns the client for the mapped resource in the namespace, if the resource is namespaced
+func (c *Client) mappedResource(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
 	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
-		resource = c.dynamic.Resource(mapping.Resource).Namespace(namespace)
-	} else {
-		resource = c.dynamic.Resource(mapping.Resource)
+		return c.dynamic.Resource(mapping.Resource).Namespace(namespace)
 	}
+	return c.dynamic.Resource(mapping.Resource)
+}
 
-	return resource, nil
+// Apply creates or updates the resources described in a YAML manifest, which can contain multiple
+// documents separated by "---". The resources are applied in dependency order (e.g. namespaces before
+// the resources in them) and the applied objects are returned in that order. If the decoding or the
+// application of some documents fails, the remaining documents are still applied and an ApplyError, with
+// the applied objects and the errors indicating the index of each document that failed, is returned.
+func (c *Client) Apply(manifest string, options ...ApplyOptions) ([]map[string]interface{}, error) {
+	opts, err := toApplyOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, errs, err := c.decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []map[string]interface{}{}
+	for _, doc := range docs {
+		obj, err := c.apply(doc.obj, doc.gvk, opts)
+		if err != nil {
+			errs = append(errs, &DocumentError{
+				Index: doc.index,
+				Kind:  doc.gvk.Kind,
+				Name:  doc.obj.GetName(),
+				Err:   err,
+			})
+			continue
+		}
+		applied = append(applied, obj)
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].Index < errs[j].Index
+		})
+		return applied, &ApplyError{Applied: applied, Errors: errs}
+	}
+	return applied, nil
 }
 
-// Apply creates a resource in a kubernetes cluster from a YAML manifest
-func (c *Client) Apply(manifest string) error {
-	uObj := &unstructured.Unstructured{}
-	_, gvk, err := c.serializer.Decode([]byte(manifest), nil, uObj)
+// ApplyObject creates or updates a resource in a kubernetes cluster from an object with its specification
+// and returns the live object
+func (c *Client) ApplyObject(obj map[string]interface{}, options ...ApplyOptions) (map[string]interface{}, error) {
+	opts, err := toApplyOptions(options)
 	if err != nil {
-		return fmt.Errorf("failed to decode manifest: %w", err)
+		return nil, err
+	}
+
+	uObj := &unstructured.Unstructured{
+		Object: obj,
 	}
+	gvk := uObj.GroupVersionKind()
 
+	return c.apply(uObj, &gvk, opts)
+}
+
+// apply applies an unstructured object of the given group, version and kind
+func (c *Client) apply(
+	uObj *unstructured.Unstructured,
+	gvk *schema.GroupVersionKind,
+	options metav1.PatchOptions,
+) (map[string]interface{}, error) {
 	name := uObj.GetName()
 	namespace := uObj.GetNamespace()
 	if namespace == "" {
-		namespace = "default"
+		namespace = c.namespace
 	}
 
 	resource, err := c.getResource(gvk.GroupKind().String(), namespace, gvk.Version)
 	if err != nil {
-		return fmt.Errorf("failed to get resource: %w", err)
+		return nil, fmt.Errorf("failed to get resource: %w", err)
 	}
 
-	_, err = resource.Apply(
+	data, err := uObj.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode object: %w", err)
+	}
+
+	resp, err := resource.Patch(
 		c.ctx,
 		name,
-		uObj,
-		metav1.ApplyOptions{
-			FieldManager: "xk6-kubernetes",
-		},
+		types.ApplyPatchType,
+		data,
+		options,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	return resp.UnstructuredContent(), nil
 }
 
-// Create creates a resource in a kubernetes cluster from an object with its specification
+// Create creates a resource in a kubernetes cluster from an object with its specification. The resource
+// is created in the version of the object's apiVersion
 func (c *Client) Create(obj map[string]interface{}) (map[string]interface{}, error) {
 	uObj := &unstructured.Unstructured{
 		Object: obj,
@@ -146,10 +371,10 @@ func (c *Client) Create(obj map[string]interface{}) (map[string]interface{}, err
 	gvk := uObj.GroupVersionKind()
 	namespace := uObj.GetNamespace()
 	if namespace == "" {
-		namespace = "default"
+		namespace = c.namespace
 	}
 
-	resource, err := c.getResource(gvk.GroupKind().String(), namespace)
+	resource, err := c.getResource(gvk.GroupKind().String(), namespace, gvk.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -183,14 +408,51 @@ func (c *Client) Get(kind string, name string, namespace string) (map[string]int
 	return resp.UnstructuredContent(), nil
 }
 
-// List returns a list of objects given its kind and namespace
-func (c *Client) List(kind string, namespace string) ([]map[string]interface{}, error) {
+// GetSubresource returns a subresource (e.g. "status" or "scale") of an object given its kind, name and namespace
+func (c *Client) GetSubresource(
+	kind string,
+	name string,
+	namespace string,
+	subresource string,
+) (map[string]interface{}, error) {
 	resource, err := c.getResource(kind, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := resource.List(c.ctx, metav1.ListOptions{})
+	resp, err := resource.Get(
+		c.ctx,
+		name,
+		metav1.GetOptions{},
+		subresource,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.UnstructuredContent(), nil
+}
+
+// List returns a list of objects given its kind and namespace.
+// Optionally, the objects can be filtered using selectors and limited in number using ListOptions
+func (c *Client) List(kind string, namespace string, options ...ListOptions) ([]map[string]interface{}, error) {
+	page, err := c.ListPage(kind, namespace, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+// ListPage returns a page of objects given its kind and namespace, and the token for retrieving the next page.
+// The size of the page is set with the Limit in the ListOptions, and the next page is requested by setting
+// the Continue token returned by the previous page
+func (c *Client) ListPage(kind string, namespace string, options ...ListOptions) (*Page, error) {
+	resource, err := c.getResource(kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := resource.List(c.ctx, toListOptions(options))
 	if err != nil {
 		return nil, err
 	}
@@ -199,21 +461,128 @@ func (c *Client) List(kind string, namespace string) ([]map[string]interface{},
 	for _, uObj := range resp.Items {
 		list = append(list, uObj.UnstructuredContent())
 	}
-	return list, nil
+	return &Page{
+		Items:              list,
+		Continue:           resp.GetContinue(),
+		ResourceVersion:    resp.GetResourceVersion(),
+		RemainingItemCount: resp.GetRemainingItemCount(),
+	}, nil
 }
 
-// Delete deletes an object given its kind, name and namespace
-func (c *Client) Delete(kind string, name string, namespace string) error {
+// Delete deletes an object given its kind, name and namespace.
+// If a Wait timeout is given in the options, waits until the object is gone.
+func (c *Client) Delete(kind string, name string, namespace string, options ...DeleteOptions) error {
+	var opts DeleteOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	deleteOptions, err := opts.toDeleteOptions()
+	if err != nil {
+		return err
+	}
+
 	resource, err := c.getResource(kind, namespace)
 	if err != nil {
 		return err
 	}
-	err = resource.Delete(c.ctx, name, metav1.DeleteOptions{})
+	err = resource.Delete(c.ctx, name, deleteOptions)
+	if err != nil {
+		return err
+	}
+
+	if opts.Wait <= 0 || opts.DryRun {
+		return nil
+	}
+	return waitDeleted(c.ctx, resource, name, time.Duration(opts.Wait)*time.Second)
+}
+
+// DeleteCollection deletes the objects of the given kind in the namespace that match the selectors
+// in the options and returns the number of objects deleted. If the resource does not support deleting
+// collections, the objects are deleted one by one in parallel and only the successful deletions are
+// counted. Otherwise, the count is the number of matching objects listed before deleting them, so it
+// can include objects removed or exclude objects created concurrently.
+func (c *Client) DeleteCollection(kind string, namespace string, options ...DeleteCollectionOptions) (int, error) {
+	var opts DeleteCollectionOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
 
-	return err
+	deleteOptions, err := opts.toDeleteOptions()
+	if err != nil {
+		return 0, err
+	}
+
+	mapping, err := c.restMapping(kind)
+	if err != nil {
+		return 0, err
+	}
+	resource := c.mappedResource(mapping, namespace)
+
+	list, err := resource.List(c.ctx, opts.toListOptions())
+	if err != nil {
+		return 0, err
+	}
+	if len(list.Items) == 0 {
+		return 0, nil
+	}
+
+	err = resource.DeleteCollection(c.ctx, deleteOptions, opts.toListOptions())
+	if apierrors.IsMethodNotSupported(err) {
+		return c.deleteItems(mapping, list.Items, deleteOptions)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(list.Items), nil
 }
 
-// Update updates a resource in a kubernetes cluster from an object with its specification
+// deleteItems deletes the given objects of the mapped resource in parallel and returns the number of objects
+// deleted. Each object is deleted in its namespace, as they can be listed from all namespaces. Objects already
+// gone are not counted as deleted but are not considered an error.
+func (c *Client) deleteItems(
+	mapping *meta.RESTMapping,
+	items []unstructured.Unstructured,
+	options metav1.DeleteOptions,
+) (int, error) {
+	var (
+		mtx     sync.Mutex
+		wg      sync.WaitGroup
+		deleted int
+		errs    []error
+	)
+
+	workers := make(chan struct{}, deleteWorkers)
+	for _, item := range items {
+		name := item.GetName()
+		resource := c.mappedResource(mapping, item.GetNamespace())
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			err := resource.Delete(c.ctx, name, options)
+			mtx.Lock()
+			defer mtx.Unlock()
+			switch {
+			case err == nil:
+				deleted++
+			case !apierrors.IsNotFound(err):
+				errs = append(errs, fmt.Errorf("failed to delete %s: %w", name, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return deleted, errors.Join(errs...)
+}
+
+// Update updates a resource in a kubernetes cluster from an object with its specification. The resource
+// is updated in the version of the object's apiVersion
 func (c *Client) Update(obj map[string]interface{}) (map[string]interface{}, error) {
 	uObj := &unstructured.Unstructured{
 		Object: obj,
@@ -222,9 +591,128 @@ func (c *Client) Update(obj map[string]interface{}) (map[string]interface{}, err
 	gvk := uObj.GroupVersionKind()
 	namespace := uObj.GetNamespace()
 	if namespace == "" {
-		namespace = "default"
+		namespace = c.namespace
+	}
+	resource, err := c.getResource(gvk.GroupKind().String(), namespace, gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := resource.Update(
+		c.ctx,
+		uObj,
+		metav1.UpdateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.UnstructuredContent(), nil
+}
+
+// Patch applies a patch to an object given its kind, name and namespace and returns the patched object.
+// The patch can be given as a string or as an object, which is encoded according to the type of patch.
+// By default, the patch is handled as a JSON merge patch.
+func (c *Client) Patch(
+	kind string,
+	name string,
+	namespace string,
+	patch interface{},
+	options ...PatchOptions,
+) (map[string]interface{}, error) {
+	var opts PatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	pt, err := opts.patchType()
+	if err != nil {
+		return nil, err
+	}
+
+	patchOptions, err := opts.toPatchOptions(pt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encodePatch(patch, pt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode patch: %w", err)
+	}
+
+	resource, err := c.getResource(kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	subresources := []string{}
+	if opts.Subresource != "" {
+		subresources = append(subresources, opts.Subresource)
+	}
+
+	resp, err := resource.Patch(
+		c.ctx,
+		name,
+		pt,
+		data,
+		patchOptions,
+		subresources...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.UnstructuredContent(), nil
+}
+
+// encodePatch returns the content of the patch encoded as expected by the patch type.
+// Patches given as strings are passed verbatim, except for CBOR apply patches, which are converted.
+func encodePatch(patch interface{}, pt types.PatchType) ([]byte, error) {
+	var data []byte
+	switch p := patch.(type) {
+	case string:
+		data = []byte(p)
+	case []byte:
+		data = p
+	default:
+		encoded, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+
+	if pt != types.ApplyCBORPatchType {
+		return data, nil
+	}
+
+	data, err := utilyaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err = json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(generic)
+}
+
+// UpdateSubresource updates a subresource (e.g. "status", "scale", "resize" or "ephemeralcontainers") of an
+// object of the given kind. The object given as input is the content of the subresource, which can be of a
+// different kind than the object (e.g. a Scale for the "scale" subresource of a Deployment), and must have
+// the name and namespace of the object.
+func (c *Client) UpdateSubresource(
+	kind string,
+	subresource string,
+	obj map[string]interface{},
+) (map[string]interface{}, error) {
+	uObj := &unstructured.Unstructured{
+		Object: obj,
+	}
+
+	namespace := uObj.GetNamespace()
+	if namespace == "" {
+		namespace = c.namespace
 	}
-	resource, err := c.getResource(gvk.GroupKind().String(), namespace)
+	resource, err := c.getResource(kind, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -233,6 +721,7 @@ func (c *Client) Update(obj map[string]interface{}) (map[string]interface{}, err
 		c.ctx,
 		uObj,
 		metav1.UpdateOptions{},
+		subresource,
 	)
 	if err != nil {
 		return nil, err
@@ -240,6 +729,25 @@ func (c *Client) Update(obj map[string]interface{}) (map[string]interface{}, err
 	return resp.UnstructuredContent(), nil
 }
 
+// PatchSubresource applies a patch to a subresource (e.g. "status" or "scale") of an object given its kind,
+// name and namespace and returns the patched subresource. The subresource overrides the one in the options.
+func (c *Client) PatchSubresource(
+	kind string,
+	name string,
+	namespace string,
+	subresource string,
+	patch interface{},
+	options ...PatchOptions,
+) (map[string]interface{}, error) {
+	var opts PatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts.Subresource = subresource
+
+	return c.Patch(kind, name, namespace, patch, opts)
+}
+
 // Structured returns a reference to a StructuredOperations interface
 func (c *Client) Structured() StructuredOperations {
 	return &structured{
@@ -278,17 +786,17 @@ func (s *structured) Get(kind string, name string, namespace string, obj interfa
 	return utils.GenericToRuntime(gObj, obj)
 }
 
-func (s *structured) Delete(kind string, name string, namespace string) error {
-	return s.client.Delete(kind, name, namespace)
+func (s *structured) Delete(kind string, name string, namespace string, options ...DeleteOptions) error {
+	return s.client.Delete(kind, name, namespace, options...)
 }
 
-func (s *structured) List(kind string, namespace string, objList interface{}) error {
+func (s *structured) List(kind string, namespace string, objList interface{}, options ...ListOptions) error {
 	objListType := reflect.ValueOf(objList).Elem().Kind().String()
 	if objListType != reflect.Slice.String() {
 		return fmt.Errorf("must provide an slice to return results but %s received", objListType)
 	}
 
-	list, err := s.client.List(kind, namespace)
+	list, err := s.client.List(kind, namespace, options...)
 	if err != nil {
 		return err
 	}
@@ -309,6 +817,22 @@ func (s *structured) List(kind string, namespace string, objList interface{}) er
 	return nil
 }
 
+func (s *structured) Patch(
+	kind string,
+	name string,
+	namespace string,
+	patch interface{},
+	obj interface{},
+	options ...PatchOptions,
+) error {
+	patched, err := s.client.Patch(kind, name, namespace, patch, options...)
+	if err != nil {
+		return err
+	}
+
+	return utils.GenericToRuntime(patched, obj)
+}
+
 func (s *structured) Update(obj interface{}) (interface{}, error) {
 	uObj, err := utils.RuntimeToGeneric(&obj)
 	if err != nil {
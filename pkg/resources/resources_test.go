@@ -2,16 +2,23 @@ package resources
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-kubernetes/internal/testutils"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	k8stest "k8s.io/client-go/testing"
 )
 
 func buildUnstructuredPod() map[string]interface{} {
@@ -226,7 +233,7 @@ func TestApply(t *testing.T) {
 				t.Errorf("failed %v", err)
 				return
 			}
-			err = c.Apply(tc.manifest)
+			_, err = c.Apply(tc.manifest)
 			if err != nil {
 				t.Errorf("failed %v", err)
 				return
@@ -245,6 +252,196 @@ func TestApply(t *testing.T) {
 	}
 }
 
+func multiDocumentManifest() string {
+	return `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: busybox
+  namespace: testns
+---
+# namespace for the deployment
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: testns
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: busybox
+  namespace: testns
+`
+}
+
+// applied records the kinds and options of the objects applied
+type applied struct {
+	kinds   []string
+	options []metav1.PatchOptions
+}
+
+// newForApplyTest returns a client that handles apply requests by returning the applied object, as the
+// fake dynamic client does not support server-side apply. The objects applied are recorded
+func newForApplyTest(record *applied) *Client {
+	fake, _ := testutils.NewFakeDynamic()
+	fake.PrependReactor("patch", "*", func(action k8stest.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stest.PatchActionImpl)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		record.kinds = append(record.kinds, obj.GetKind())
+		record.options = append(record.options, patch.GetPatchOptions())
+		return true, obj, nil
+	})
+
+	return NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+}
+
+func TestApplyManifest(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		test          string
+		manifest      string
+		expectedKinds []string
+		errorIndexes  []int
+	}{
+		{
+			test:          "Apply in dependency order",
+			manifest:      multiDocumentManifest(),
+			expectedKinds: []string{"Namespace", "ConfigMap", "Deployment"},
+		},
+		{
+			test:          "Apply remaining documents on error",
+			manifest:      podManifest() + "---\napiVersion: v1\nkind: Unknown\nmetadata:\n  name: unknown\n",
+			expectedKinds: []string{"Pod"},
+			errorIndexes:  []int{1},
+		},
+		{
+			test:          "Apply remaining documents on decode error",
+			manifest:      podManifest() + "---\nkind: [\n",
+			expectedKinds: []string{"Pod"},
+			errorIndexes:  []int{1},
+		},
+		{
+			test: "Decode and apply errors",
+			manifest: "kind: [\n---\n" + podManifest() +
+				"---\napiVersion: v1\nkind: Unknown\nmetadata:\n  name: unknown\n",
+			expectedKinds: []string{"Pod"},
+			errorIndexes:  []int{0, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			record := &applied{kinds: []string{}}
+			c := newForApplyTest(record)
+
+			objs, err := c.Apply(tc.manifest)
+			if len(tc.errorIndexes) == 0 && err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+			if len(tc.errorIndexes) > 0 {
+				var applyErr *ApplyError
+				if !errors.As(err, &applyErr) || len(applyErr.Applied) != len(objs) {
+					t.Errorf("expected an apply error with the applied objects but %v returned", err)
+					return
+				}
+				indexes := []int{}
+				for _, docErr := range applyErr.Errors {
+					indexes = append(indexes, docErr.Index)
+				}
+				if !reflect.DeepEqual(indexes, tc.errorIndexes) {
+					t.Errorf("expected errors in documents %v but %v returned", tc.errorIndexes, indexes)
+					return
+				}
+			}
+
+			if !reflect.DeepEqual(record.kinds, tc.expectedKinds) {
+				t.Errorf("expected kinds %v applied but %v received", tc.expectedKinds, record.kinds)
+				return
+			}
+			if len(objs) != len(tc.expectedKinds) {
+				t.Errorf("expected %d objects returned but %d received", len(tc.expectedKinds), len(objs))
+				return
+			}
+		})
+	}
+}
+
+func TestApplyObject(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		test        string
+		options     []ApplyOptions
+		expected    metav1.PatchOptions
+		expectError bool
+	}{
+		{
+			test:     "Default options",
+			options:  []ApplyOptions{},
+			expected: metav1.PatchOptions{FieldManager: "xk6-kubernetes", Force: new(bool)},
+		},
+		{
+			test: "Force with field manager",
+			options: []ApplyOptions{
+				{Force: true, FieldManager: "test", DryRun: true, FieldValidation: "strict"},
+			},
+			expected: metav1.PatchOptions{
+				FieldManager:    "test",
+				Force:           func() *bool { b := true; return &b }(),
+				DryRun:          []string{metav1.DryRunAll},
+				FieldValidation: metav1.FieldValidationStrict,
+			},
+		},
+		{
+			test:        "Invalid field validation",
+			options:     []ApplyOptions{{FieldValidation: "none"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			record := &applied{}
+			c := newForApplyTest(record)
+
+			obj, err := c.ApplyObject(buildUnstructuredPod(), tc.options...)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but none returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+			if name != "busybox" {
+				t.Errorf("invalid object returned. Expected %s Returned %s", "busybox", name)
+				return
+			}
+			if len(record.options) != 1 || !reflect.DeepEqual(record.options[0], tc.expected) {
+				t.Errorf("expected options %v but %v received", tc.expected, record.options)
+				return
+			}
+		})
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	t.Parallel()
 
@@ -331,7 +528,7 @@ func TestDelete(t *testing.T) {
 
 			// check the object was added to the fake client's object tracker
 			_, err = fake.Tracker().Get(tc.resource, tc.ns, tc.name)
-			if !errors.IsNotFound(err) {
+			if !apierrors.IsNotFound(err) {
 				t.Errorf("error retrieving object %v", err)
 				return
 			}
@@ -339,6 +536,186 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestDeleteWithOptions(t *testing.T) {
+	t.Parallel()
+	podResource := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	testCases := []struct {
+		test        string
+		options     DeleteOptions
+		ignore      bool
+		delay       time.Duration
+		expectError bool
+	}{
+		{
+			test:    "Delete with options",
+			options: DeleteOptions{GracePeriodSeconds: new(int64), PropagationPolicy: "foreground"},
+		},
+		{
+			test:        "Invalid propagation policy",
+			options:     DeleteOptions{PropagationPolicy: "cascade"},
+			expectError: true,
+		},
+		{
+			test:    "Wait for deleted object",
+			options: DeleteOptions{Wait: 5},
+		},
+		{
+			test:    "Wait for object deleted later",
+			options: DeleteOptions{Wait: 5},
+			ignore:  true,
+			delay:   time.Second,
+		},
+		{
+			test:        "Timeout waiting for object deleted",
+			options:     DeleteOptions{Wait: 1},
+			ignore:      true,
+			delay:       10 * time.Second,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, err := testutils.NewFakeDynamic(buildPod())
+			if err != nil {
+				t.Errorf("unexpected error creating fake client %v", err)
+				return
+			}
+			c := NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+
+			if tc.ignore {
+				// keep the object after the delete request, and remove it after the delay
+				fake.PrependReactor("delete", "pods", func(_ k8stest.Action) (bool, runtime.Object, error) {
+					return true, nil, nil
+				})
+				go func() {
+					time.Sleep(tc.delay)
+					_ = fake.Tracker().Delete(podResource, "testns", "busybox")
+				}()
+			}
+
+			err = c.Delete("Pod", "busybox", "testns", tc.options)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but none returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			_, err = fake.Tracker().Get(podResource, "testns", "busybox")
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("object was not deleted %v", err)
+				return
+			}
+		})
+	}
+}
+
+func TestDeleteCollection(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		test      string
+		supported bool
+		namespace string
+		selector  string
+		expected  int
+		remaining int
+	}{
+		{
+			test:      "Delete collection",
+			supported: true,
+			namespace: "testns",
+			selector:  "app=xk6",
+			expected:  2,
+			remaining: 4,
+		},
+		{
+			test:      "Delete items if collection not supported",
+			supported: false,
+			namespace: "testns",
+			selector:  "app=xk6",
+			expected:  2,
+			remaining: 2,
+		},
+		{
+			test:      "Nothing to delete",
+			supported: false,
+			namespace: "testns",
+			selector:  "app=none",
+			expected:  0,
+			remaining: 4,
+		},
+		{
+			test:      "Delete items in all namespaces",
+			supported: false,
+			namespace: "",
+			selector:  "app=xk6",
+			expected:  3,
+			remaining: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			objs := []runtime.Object{buildPod()}
+			for _, name := range []string{"pod1", "pod2", "otherns/pod3"} {
+				pod := buildPod()
+				if namespace, podName, found := strings.Cut(name, "/"); found {
+					pod.Namespace = namespace
+					name = podName
+				}
+				pod.Name = name
+				pod.Labels = map[string]string{"app": "xk6"}
+				objs = append(objs, pod)
+			}
+			fake, err := testutils.NewFakeDynamic(objs...)
+			if err != nil {
+				t.Errorf("unexpected error creating fake client %v", err)
+				return
+			}
+			// the fake client does not implement delete-collection, so it either accepts the
+			// request without deleting objects or rejects it as not supported
+			fake.PrependReactor("delete-collection", "pods", func(_ k8stest.Action) (bool, runtime.Object, error) {
+				if tc.supported {
+					return true, nil, nil
+				}
+				return true, nil, apierrors.NewMethodNotSupported(schema.GroupResource{Resource: "pods"}, "delete")
+			})
+			c := NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+
+			deleted, err := c.DeleteCollection("Pod", tc.namespace, DeleteCollectionOptions{LabelSelector: tc.selector})
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+			if deleted != tc.expected {
+				t.Errorf("expected %d objects deleted but %d returned", tc.expected, deleted)
+				return
+			}
+
+			list, err := c.List("Pod", "")
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+			if len(list) != tc.remaining {
+				t.Errorf("expected %d objects remaining but %d found", tc.remaining, len(list))
+				return
+			}
+		})
+	}
+}
+
 func TestGet(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -414,6 +791,64 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestSubresources(t *testing.T) {
+	t.Parallel()
+
+	fake, err := testutils.NewFakeDynamic(buildPod())
+	if err != nil {
+		t.Errorf("unexpected error creating fake client %v", err)
+		return
+	}
+	c := NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+
+	// checks the last action sent to the fake client targeted the subresource
+	checkSubresource := func(verb string) {
+		actions := fake.Actions()
+		action := actions[len(actions)-1]
+		if action.GetVerb() != verb || action.GetSubresource() != "status" {
+			t.Errorf("expected %s of status subresource but %s of %q sent", verb, action.GetVerb(), action.GetSubresource())
+		}
+	}
+
+	status, err := c.GetSubresource("Pod", "busybox", "testns", "status")
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	checkSubresource("get")
+
+	err = unstructured.SetNestedField(status, string(corev1.PodRunning), "status", "phase")
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+		return
+	}
+	updated, err := c.UpdateSubresource("Pod", "status", status)
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	checkSubresource("update")
+
+	phase, _, _ := unstructured.NestedString(updated, "status", "phase")
+	if phase != string(corev1.PodRunning) {
+		t.Errorf("pod status not updated")
+		return
+	}
+
+	patched, err := c.PatchSubresource("Pod", "busybox", "testns", "status", `{"status":{"phase":"Succeeded"}}`)
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	checkSubresource("patch")
+
+	phase, _, _ = unstructured.NestedString(patched, "status", "phase")
+	if phase != string(corev1.PodSucceeded) {
+		t.Errorf("pod status not patched")
+		return
+	}
+}
+
 func TestList(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -480,6 +915,109 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListWithOptions(t *testing.T) {
+	t.Parallel()
+
+	labeled := buildPod()
+	labeled.Name = "labeled"
+	labeled.Labels = map[string]string{"app": "xk6"}
+	c, err := newForTest(buildPod(), labeled)
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+
+	list, err := c.List("Pod", "testns", ListOptions{LabelSelector: "app=xk6"})
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	if len(list) != 1 {
+		t.Errorf("expect %d pods but %d received", 1, len(list))
+		return
+	}
+
+	page, err := c.ListPage("Pod", "testns")
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("expect %d pods but %d received", 2, len(page.Items))
+		return
+	}
+	if page.Continue != "" {
+		t.Errorf("expect no continue token but %q received", page.Continue)
+		return
+	}
+}
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		test        string
+		patch       interface{}
+		options     PatchOptions
+		expectError bool
+	}{
+		{
+			test:    "Merge patch from object",
+			patch:   map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "xk6"}}},
+			options: PatchOptions{},
+		},
+		{
+			test:    "Merge patch from string",
+			patch:   `{"metadata":{"labels":{"app":"xk6"}}}`,
+			options: PatchOptions{Type: "merge"},
+		},
+		{
+			test:    "JSON patch",
+			patch:   `[{"op":"add","path":"/metadata/labels","value":{"app":"xk6"}}]`,
+			options: PatchOptions{Type: "json"},
+		},
+		{
+			test:        "Unsupported patch type",
+			patch:       `{}`,
+			options:     PatchOptions{Type: "unknown"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+			c, err := newForTest(buildPod())
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			patched, err := c.Patch("Pod", "busybox", "testns", tc.patch, tc.options)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but none returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("failed %v", err)
+				return
+			}
+
+			label, found, err := unstructured.NestedString(patched, "metadata", "labels", "app")
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+			if !found || label != "xk6" {
+				t.Errorf("pod label was not patched")
+				return
+			}
+		})
+	}
+}
+
 func TestStructuredCreate(t *testing.T) {
 	t.Parallel()
 
@@ -570,6 +1108,27 @@ func TestStructuredDelete(t *testing.T) {
 	}
 }
 
+func TestStructuredPatch(t *testing.T) {
+	t.Parallel()
+	// initialize with pod
+	c, err := newForTest(buildPod())
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+
+	pod := &corev1.Pod{}
+	err = c.Structured().Patch("Pod", "busybox", "testns", `{"status":{"phase":"Failed"}}`, pod)
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	if pod.Status.Phase != corev1.PodFailed {
+		t.Errorf("pod status not patched")
+		return
+	}
+}
+
 func TestStructuredUpdate(t *testing.T) {
 	t.Parallel()
 	// initialize with pod
@@ -598,3 +1157,180 @@ func TestStructuredUpdate(t *testing.T) {
 		return
 	}
 }
+
+// resettableMapper is a RESTMapper that does not know any kind until it is reset
+type resettableMapper struct {
+	testutils.FakeRESTMapper
+	resets int
+}
+
+func (m *resettableMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	if m.resets == 0 {
+		return nil, &meta.NoKindMatchError{GroupKind: gk}
+	}
+	return m.FakeRESTMapper.RESTMapping(gk, versions...)
+}
+
+func (m *resettableMapper) Reset() {
+	m.resets++
+}
+
+func TestRefreshDiscoveryOnNoKindMatch(t *testing.T) {
+	t.Parallel()
+	fake, err := testutils.NewFakeDynamic()
+	if err != nil {
+		t.Errorf("unexpected error creating fake client %v", err)
+		return
+	}
+	mapper := &resettableMapper{}
+	c := NewFromClient(context.TODO(), fake).WithMapper(mapper)
+
+	_, err = c.Create(buildUnstructuredPod())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if mapper.resets != 1 {
+		t.Errorf("expected mapper to be reset once but was reset %d times", mapper.resets)
+		return
+	}
+
+	// kinds unknown after reset are retried only once
+	_, err = c.Get("Unknown", "name", "testns")
+	if !meta.IsNoMatchError(err) {
+		t.Errorf("expected no match error getting unknown kind but got %v", err)
+		return
+	}
+	if mapper.resets != 2 {
+		t.Errorf("expected mapper to be reset twice but was reset %d times", mapper.resets)
+	}
+}
+
+func TestRefreshOnNoKindMatchIsDeduplicated(t *testing.T) {
+	t.Parallel()
+	fake, err := testutils.NewFakeDynamic()
+	if err != nil {
+		t.Errorf("unexpected error creating fake client %v", err)
+		return
+	}
+	mapper := &resettableMapper{}
+	shared := NewSharedRESTMapper(mapper)
+	c := NewFromClient(context.TODO(), fake).WithMapper(shared)
+
+	// each lookup of an unknown kind refreshes the discovery once
+	for i := 1; i <= 2; i++ {
+		_, err = c.Get("Unknown", "name", "testns")
+		if !meta.IsNoMatchError(err) {
+			t.Errorf("expected no match error getting unknown kind but got %v", err)
+			return
+		}
+		if mapper.resets != i {
+			t.Errorf("expected mapper to be reset %d times but was reset %d times", i, mapper.resets)
+			return
+		}
+	}
+
+	// a refresh is skipped if the mapper was refreshed after the lookup started
+	generation := shared.Generation()
+	if !c.RefreshDiscovery() {
+		t.Errorf("expected mapper to be refreshed")
+		return
+	}
+	shared.ResetIfUnchanged(generation)
+	if mapper.resets != 3 {
+		t.Errorf("expected mapper to be reset 3 times but was reset %d times", mapper.resets)
+	}
+}
+
+func TestKindResolution(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test        string
+		kind        string
+		expectError bool
+		resource    string
+	}{
+		{test: "kind", kind: "Deployment", resource: "deployments"},
+		{test: "kind and group", kind: "Deployment.apps", resource: "deployments"},
+		{test: "kind, version and group", kind: "Deployment.v1.apps", resource: "deployments"},
+		{test: "group, version and kind", kind: "apps/v1/Deployment", resource: "deployments"},
+		{test: "version and kind in core group", kind: "v1/Pod", resource: "pods"},
+		{test: "plural", kind: "pods", resource: "pods"},
+		{test: "singular", kind: "deployment", resource: "deployments"},
+		{test: "short name", kind: "svc", resource: "services"},
+		{test: "plural and group", kind: "deployments.apps", resource: "deployments"},
+		{test: "unknown kind", kind: "Gateway", expectError: true},
+		{test: "invalid group version", kind: "apps/v1/beta/Deployment", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, err := testutils.NewFakeDynamic()
+			if err != nil {
+				t.Errorf("unexpected error creating fake client %v", err)
+				return
+			}
+			c := NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+
+			mapping, err := c.restMapping(tc.kind)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if mapping.Resource.Resource != tc.resource {
+				t.Errorf("expected resource %s but got %s", tc.resource, mapping.Resource.Resource)
+			}
+		})
+	}
+}
+
+// versionsMapper is a RESTMapper that records the versions requested for each kind
+type versionsMapper struct {
+	testutils.FakeRESTMapper
+	versions map[string][]string
+}
+
+func (m *versionsMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	m.versions[gk.Kind] = versions
+	return m.FakeRESTMapper.RESTMapping(gk, versions...)
+}
+
+func TestExplicitVersion(t *testing.T) {
+	t.Parallel()
+	fake, err := testutils.NewFakeDynamic()
+	if err != nil {
+		t.Errorf("unexpected error creating fake client %v", err)
+		return
+	}
+	mapper := &versionsMapper{versions: map[string][]string{}}
+	c := NewFromClient(context.TODO(), fake).WithMapper(mapper)
+
+	pod := buildUnstructuredPod()
+	pod["apiVersion"] = "v1beta1"
+	_, err = c.Create(pod)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(mapper.versions["Pod"], []string{"v1beta1"}) {
+		t.Errorf("expected object created in version v1beta1 but got %v", mapper.versions["Pod"])
+	}
+
+	_, err = c.Get("Deployment.v1beta1.apps", "nginx", "testns")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found error but got %v", err)
+		return
+	}
+	if !reflect.DeepEqual(mapper.versions["Deployment"], []string{"v1beta1"}) {
+		t.Errorf("expected version v1beta1 but got %v", mapper.versions["Deployment"])
+	}
+}
@@ -0,0 +1,100 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// waitDeleted waits until the named object is gone or the timeout expires. The existence of the object
+// is checked with a list request, and the deletion is then observed by watching from the list's
+// resource version, so the deletion can't be missed between both requests.
+func waitDeleted(ctx context.Context, resource dynamic.ResourceInterface, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	for {
+		list, err := resource.List(ctx, metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			return deleteWaitError(ctx, name, err)
+		}
+
+		found := false
+		for _, item := range list.Items {
+			if item.GetName() == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		deleted, err := watchDeleted(ctx, resource, name, selector, list.GetResourceVersion())
+		if err != nil {
+			return deleteWaitError(ctx, name, err)
+		}
+		if deleted {
+			return nil
+		}
+	}
+}
+
+// watchDeleted watches the named object from the given resource version and returns true when it is
+// deleted. Returns false if the watch is closed or expires before the object is deleted.
+func watchDeleted(
+	ctx context.Context,
+	resource dynamic.ResourceInterface,
+	name string,
+	selector string,
+	resourceVersion string,
+) (bool, error) {
+	watcher, err := resource.Watch(ctx, metav1.ListOptions{
+		FieldSelector:   selector,
+		ResourceVersion: resourceVersion,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch event.Type {
+			case watch.Deleted:
+				if obj, isObj := event.Object.(metav1.Object); isObj && obj.GetName() == name {
+					return true, nil
+				}
+			case watch.Error:
+				statusErr := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(statusErr) || apierrors.IsGone(statusErr) {
+					return false, nil
+				}
+				return false, statusErr
+			default:
+			}
+		}
+	}
+}
+
+// deleteWaitError returns a timeout error if the wait for the deletion expired, or the error otherwise
+func deleteWaitError(ctx context.Context, name string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timeout waiting for %s to be deleted", name)
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package resources
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/pkg/utils"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// watchRetryBackoff is the time to wait before retrying a failed attempt to restart a watch
+const watchRetryBackoff = time.Second
+
+// WatchEvent describes a change in a watched object
+type WatchEvent struct {
+	// Type of event: "ADDED", "MODIFIED", "DELETED", "BOOKMARK" or "ERROR"
+	Type string `js:"type"`
+	// Object changed. For BOOKMARK events, only the resource version is set. For ERROR events,
+	// it is the status returned by the server
+	Object map[string]interface{} `js:"object"`
+}
+
+// WatchOperations defines the functions for watching the changes in any kind of Kubernetes object
+type WatchOperations interface {
+	Watch(kind string, namespace string, options ...WatchOptions) (*Watcher, error)
+}
+
+// Watcher delivers the events of a watch. The watch is restarted from the last resource version
+// received if it is closed by the server, and from the current state if the resource version expires.
+type Watcher struct {
+	events   chan WatchEvent
+	cancel   context.CancelFunc
+	resource dynamic.ResourceInterface
+	options  metav1.ListOptions
+}
+
+// Watch starts watching the objects of the given kind in the namespace, optionally filtered by selectors
+func (c *Client) Watch(kind string, namespace string, options ...WatchOptions) (*Watcher, error) {
+	var opts WatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	resource, err := c.getResource(kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	w := &Watcher{
+		events:   make(chan WatchEvent),
+		cancel:   cancel,
+		resource: resource,
+		options:  opts.toListOptions(),
+	}
+
+	// the first watch is started before returning for reporting errors such as invalid selectors
+	current, err := resource.Watch(ctx, w.options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go w.run(ctx, current)
+
+	return w, nil
+}
+
+// Events returns the channel the events are delivered to. The channel is closed when the watcher is stopped
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Stop stops watching. It is safe to call it multiple times
+func (w *Watcher) Stop() {
+	w.cancel()
+}
+
+// run forwards the events of the watch and restarts it when it is closed, until the watcher is stopped
+func (w *Watcher) run(ctx context.Context, current watch.Interface) {
+	defer close(w.events)
+
+	for {
+		expired := w.forward(ctx, current)
+		current.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+		if expired {
+			w.options.ResourceVersion = ""
+		}
+
+		current = w.restart(ctx)
+		if current == nil {
+			return
+		}
+	}
+}
+
+// restart starts a new watch, retrying until it succeeds or the watcher is stopped. Returns nil if stopped
+func (w *Watcher) restart(ctx context.Context) watch.Interface {
+	for {
+		current, err := w.resource.Watch(ctx, w.options)
+		if err == nil {
+			return current
+		}
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			w.options.ResourceVersion = ""
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryBackoff):
+		}
+	}
+}
+
+// forward delivers the events received from the watch until it is closed or the watcher is stopped,
+// keeping track of the last resource version received. Returns true if the resource version expired.
+func (w *Watcher) forward(ctx context.Context, current watch.Interface) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-current.ResultChan():
+			if !ok {
+				return false
+			}
+
+			if event.Type == watch.Error {
+				statusErr := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(statusErr) || apierrors.IsGone(statusErr) {
+					return true
+				}
+			} else if obj, isObj := event.Object.(metav1.Object); isObj {
+				w.options.ResourceVersion = obj.GetResourceVersion()
+			}
+
+			if !w.deliver(ctx, WatchEvent{Type: string(event.Type), Object: toGeneric(event.Object)}) {
+				return false
+			}
+		}
+	}
+}
+
+// deliver sends an event to the events channel. Returns false if the watcher was stopped
+func (w *Watcher) deliver(ctx context.Context, event WatchEvent) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.events <- event:
+		return true
+	}
+}
+
+// toGeneric returns the content of a runtime object as a generic object
+func toGeneric(obj runtime.Object) map[string]interface{} {
+	if uObj, isUnstructured := obj.(*unstructured.Unstructured); isUnstructured {
+		return uObj.UnstructuredContent()
+	}
+	generic, err := utils.RuntimeToGeneric(obj)
+	if err != nil {
+		return nil
+	}
+	return generic
+}
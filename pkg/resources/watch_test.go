@@ -0,0 +1,166 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-kubernetes/internal/testutils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stest "k8s.io/client-go/testing"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	c, err := newForTest()
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+
+	watcher, err := c.Watch("Pod", "testns", WatchOptions{})
+	if err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+
+	if _, err = c.Create(buildUnstructuredPod()); err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+	if err = c.Delete("Pod", "busybox", "testns"); err != nil {
+		t.Errorf("failed %v", err)
+		return
+	}
+
+	for _, expected := range []string{"ADDED", "DELETED"} {
+		select {
+		case event := <-watcher.Events():
+			if event.Type != expected {
+				t.Errorf("expected %s event but %s received", expected, event.Type)
+				return
+			}
+			name, _, _ := unstructured.NestedString(event.Object, "metadata", "name")
+			if name != "busybox" {
+				t.Errorf("expected event for %s but %s received", "busybox", name)
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("timeout waiting for %s event", expected)
+			return
+		}
+	}
+
+	watcher.Stop()
+	select {
+	case _, ok := <-watcher.Events():
+		if ok {
+			t.Errorf("unexpected event after stopping")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("events not closed after stopping")
+	}
+}
+
+func TestWatchRestart(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test            string
+		end             func(w *watch.FakeWatcher)
+		resourceVersion string
+	}{
+		{
+			test:            "resume from last resource version when closed",
+			end:             func(w *watch.FakeWatcher) { w.Stop() },
+			resourceVersion: "5",
+		},
+		{
+			test: "restart from current state when resource version expired",
+			end: func(w *watch.FakeWatcher) {
+				w.Error(&metav1.Status{
+					Status: metav1.StatusFailure,
+					Reason: metav1.StatusReasonExpired,
+					Code:   http.StatusGone,
+				})
+			},
+			resourceVersion: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			fake, err := testutils.NewFakeDynamic()
+			if err != nil {
+				t.Errorf("unexpected error creating fake client %v", err)
+				return
+			}
+
+			var (
+				mu       sync.Mutex
+				watchers = []*watch.FakeWatcher{watch.NewFake(), watch.NewFake()}
+				started  = make(chan string, len(watchers))
+			)
+			fake.PrependWatchReactor("pods", func(action k8stest.Action) (bool, watch.Interface, error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if len(watchers) == 0 {
+					return true, nil, errors.New("unexpected watch")
+				}
+				current := watchers[0]
+				watchers = watchers[1:]
+				started <- action.(k8stest.WatchAction).GetWatchRestrictions().ResourceVersion
+				return true, current, nil
+			})
+			first := watchers[0]
+
+			c := NewFromClient(context.TODO(), fake).WithMapper(&testutils.FakeRESTMapper{})
+			watcher, err := c.Watch("Pod", "testns", WatchOptions{})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+			defer watcher.Stop()
+			<-started
+
+			pod := &unstructured.Unstructured{}
+			pod.SetAPIVersion("v1")
+			pod.SetKind("Pod")
+			pod.SetName("busybox")
+			pod.SetResourceVersion("5")
+			first.Add(pod)
+
+			select {
+			case event := <-watcher.Events():
+				if event.Type != "ADDED" {
+					t.Errorf("expected ADDED event but %s received", event.Type)
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("timeout waiting for ADDED event")
+				return
+			}
+
+			tc.end(first)
+
+			select {
+			case resourceVersion := <-started:
+				if resourceVersion != tc.resourceVersion {
+					t.Errorf("expected restart from resource version %q but was %q", tc.resourceVersion, resourceVersion)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("timeout waiting for restart")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/v2/metrics"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// rateLimiterNone disables the client-side rate limiter
+const rateLimiterNone = "none"
+
+// throttleMetricsLimiter is a flowcontrol.RateLimiter that reports the time spent waiting for the
+// wrapped rate limiter
+type throttleMetricsLimiter struct {
+	flowcontrol.RateLimiter
+	metrics *kubernetesMetrics
+}
+
+// Wait waits for the wrapped rate limiter and reports the time waited to the VU in the client scope of
+// the context. Metrics are only reported in the VU context
+func (l *throttleMetricsLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	waited := time.Since(start)
+
+	scope := clientScopeFrom(ctx)
+	state := scope.state()
+	if state == nil || l.metrics == nil {
+		return err
+	}
+
+	tagsAndMeta := state.Tags.GetCurrentValues()
+	metrics.PushIfNotDone(scope.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: l.metrics.ThrottleWait, Tags: tagsAndMeta.Tags},
+		Time:       time.Now(),
+		Value:      metrics.D(waited),
+		Metadata:   tagsAndMeta.Metadata,
+	})
+
+	return err
+}
+
+// configureRateLimiter sets the client-side rate limiter of the rest config from the options. The rate
+// limiter is shared by all the clients created from the config
+func configureRateLimiter(config *rest.Config, options KubeConfig, m *kubernetesMetrics) error {
+	if options.QPS < 0 {
+		return fmt.Errorf("qps must be a positive number: %v", options.QPS)
+	}
+	if options.Burst < 0 {
+		return fmt.Errorf("burst must be a positive number: %d", options.Burst)
+	}
+
+	switch strings.ToLower(options.RateLimiter) {
+	case "":
+	case rateLimiterNone:
+		// a negative QPS disables the rate limiter in the clients
+		config.QPS = -1
+		config.RateLimiter = nil
+		return nil
+	default:
+		return fmt.Errorf("unknown rate limiter %q. Only %q is supported", options.RateLimiter, rateLimiterNone)
+	}
+
+	qps := config.QPS
+	if options.QPS > 0 {
+		qps = options.QPS
+	}
+	if qps <= 0 {
+		qps = rest.DefaultQPS
+	}
+	burst := config.Burst
+	if options.Burst > 0 {
+		burst = options.Burst
+	}
+	if burst <= 0 {
+		burst = rest.DefaultBurst
+	}
+
+	config.QPS = qps
+	config.Burst = burst
+	config.RateLimiter = newRateLimiter(qps, burst, m)
+
+	return nil
+}
+
+// newRateLimiter returns a token bucket rate limiter that reports the time waited for it
+func newRateLimiter(qps float32, burst int, m *kubernetesMetrics) flowcontrol.RateLimiter {
+	return &throttleMetricsLimiter{
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		metrics:     m,
+	}
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/v2/js/modulestest"
+	"go.k6.io/k6/v2/lib"
+	"go.k6.io/k6/v2/metrics"
+	"k8s.io/client-go/rest"
+)
+
+func TestConfigureRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		test        string
+		options     KubeConfig
+		expectError bool
+		expectedQPS float32
+		burst       int
+	}{
+		{
+			test:        "defaults",
+			options:     KubeConfig{},
+			expectedQPS: rest.DefaultQPS,
+			burst:       rest.DefaultBurst,
+		},
+		{
+			test:        "qps and burst",
+			options:     KubeConfig{QPS: 100, Burst: 200},
+			expectedQPS: 100,
+			burst:       200,
+		},
+		{
+			test:        "no rate limiter",
+			options:     KubeConfig{RateLimiter: "none"},
+			expectedQPS: -1,
+		},
+		{
+			test:        "negative qps",
+			options:     KubeConfig{QPS: -1},
+			expectError: true,
+		},
+		{
+			test:        "unknown rate limiter",
+			options:     KubeConfig{RateLimiter: "leaky"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			config := &rest.Config{}
+			err := configureRateLimiter(config, tc.options, nil)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedQPS, config.QPS)
+			if tc.expectedQPS < 0 {
+				require.Nil(t, config.RateLimiter)
+				return
+			}
+			require.Equal(t, tc.burst, config.Burst)
+			require.Equal(t, tc.expectedQPS, config.RateLimiter.QPS())
+		})
+	}
+}
+
+func TestThrottleWaitIsReported(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	m, err := registerMetrics(registry)
+	require.NoError(t, err)
+
+	samples := make(chan metrics.SampleContainer, 10)
+	vu := &modulestest.VU{
+		CtxField: context.Background(),
+		StateField: &lib.State{
+			Samples: samples,
+			Tags:    lib.NewVUStateTags(registry.RootTagSet()),
+		},
+	}
+
+	config := &rest.Config{}
+	require.NoError(t, configureRateLimiter(config, KubeConfig{}, m))
+	ctx := withClientScope(context.Background(), &clientScope{vu: vu})
+	require.NoError(t, config.RateLimiter.Wait(ctx))
+
+	container := <-samples
+	reported := container.GetSamples()
+	require.Len(t, reported, 1)
+	require.Equal(t, "k8s_api_throttle_wait", reported[0].Metric.Name)
+}
@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"time"
+)
+
+// timeoutTransport is a http.RoundTripper that limits the time of each request, including reading its
+// response. Long-running requests that stream their responses, such as watches, are not limited
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// newTimeoutTransport returns a function for wrapping the transport of a rest.Config
+func newTimeoutTransport(timeout time.Duration) func(http.RoundTripper) http.RoundTripper {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &timeoutTransport{
+			base:    base,
+			timeout: timeout,
+		}
+	}
+}
+
+// RoundTrip sends the request, cancelling it if the response is not read before the timeout expires
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isStreaming(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelOnClose is a response body that cancels the context of its request when closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the context of the request
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// isStreaming returns true if the response of the request is streamed until the client closes it, as in
+// watches, followed logs or commands executed in containers
+func isStreaming(req *http.Request) bool {
+	query := req.URL.Query()
+	if parseRequest(req).verb == "watch" || query.Get("follow") == "true" {
+		return true
+	}
+	switch path.Base(req.URL.Path) {
+	case "attach", "exec", "portforward":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutTransport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// responses are streamed beyond the timeout
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: newTimeoutTransport(50 * time.Millisecond)(http.DefaultTransport)}
+
+	testCases := []struct {
+		test        string
+		path        string
+		expectError bool
+	}{
+		{test: "get", path: "/api/v1/namespaces/testns/pods/busybox", expectError: true},
+		{test: "list", path: "/api/v1/namespaces/testns/pods", expectError: true},
+		{test: "watch", path: "/api/v1/namespaces/testns/pods?watch=true"},
+		{test: "watch path", path: "/api/v1/watch/namespaces/testns/pods"},
+		{test: "follow logs", path: "/api/v1/namespaces/testns/pods/busybox/log?follow=true"},
+		{test: "exec", path: "/api/v1/namespaces/testns/pods/busybox/exec?command=sh"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := client.Get(server.URL + tc.path) //nolint:noctx
+			require.NoError(t, err)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+
+			_, err = io.ReadAll(resp.Body)
+			if tc.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"errors"
+
+	"github.com/grafana/sobek"
+
+	"github.com/grafana/xk6-kubernetes/pkg/resources"
+)
+
+// WatchHandle is returned to JavaScript for controlling a watch
+type WatchHandle struct {
+	watcher *resources.Watcher
+}
+
+// Stop stops the watch. No more events are delivered to the callback after it is stopped
+func (h *WatchHandle) Stop() {
+	h.watcher.Stop()
+}
+
+// Watch watches the objects of the given kind in the namespace and invokes the callback with each event
+// received. The options are optional and the callback can be passed in their place. The callback is
+// invoked in the VU's event loop, which is kept running until the watch is stopped.
+func (k *Kubernetes) Watch(
+	kind string,
+	namespace string,
+	options sobek.Value,
+	callback sobek.Value,
+) (*WatchHandle, error) {
+	rt := k.vu.Runtime()
+
+	if _, isCallable := sobek.AssertFunction(options); isCallable && sobek.IsUndefined(callback) {
+		options, callback = sobek.Undefined(), options
+	}
+	fn, isCallable := sobek.AssertFunction(callback)
+	if !isCallable {
+		return nil, errors.New("watch expects a callback function")
+	}
+
+	var opts resources.WatchOptions
+	if !sobek.IsUndefined(options) && !sobek.IsNull(options) {
+		if err := rt.ExportTo(options, &opts); err != nil {
+			return nil, err
+		}
+	}
+
+	watcher, err := k.Kubernetes.Watch(kind, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// each event is delivered by enqueuing a call to the callback in the event loop. The enqueued function
+	// registers the next callback before invoking the JS callback, so the event loop is kept running
+	registered := make(chan func(func() error), 1)
+	registered <- k.vu.RegisterCallback()
+	go func() {
+		for event := range watcher.Events() {
+			enqueue := <-registered
+			enqueue(func() error {
+				registered <- k.vu.RegisterCallback()
+				_, err := fn(sobek.Undefined(), rt.ToValue(event))
+				if err != nil {
+					watcher.Stop()
+				}
+				return err
+			})
+		}
+
+		// release the event loop once the watch is stopped
+		enqueue := <-registered
+		enqueue(func() error { return nil })
+	}()
+
+	return &WatchHandle{watcher: watcher}, nil
+}